@@ -2,4 +2,532 @@ ALTER TABLE purchase_order_item_reconciliation
 CHANGE COLUMN purcahse_order_id purchase_order_id INT;
 
 ALTER TABLE goods_received_note
-CHANGE COLUMN purcahse_order_id purchase_order_id INT;
\ No newline at end of file
+CHANGE COLUMN purcahse_order_id purchase_order_id INT;
+
+CREATE TABLE invoice_return (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    invoice_id INT NOT NULL,
+    user_id INT NOT NULL,
+    created DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    cost_price DECIMAL(12, 2) NOT NULL DEFAULT 0,
+    price_before_discount DECIMAL(12, 2) NOT NULL DEFAULT 0,
+    discount DECIMAL(5, 2) NOT NULL DEFAULT 0,
+    price_after_discount DECIMAL(12, 2) NOT NULL DEFAULT 0,
+    transaction_id INT,
+    remarks TEXT,
+    FOREIGN KEY (invoice_id) REFERENCES invoice(id),
+    FOREIGN KEY (user_id) REFERENCES user(id),
+    FOREIGN KEY (transaction_id) REFERENCES transaction(id)
+);
+
+CREATE TABLE invoice_return_item (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    invoice_return_id INT NOT NULL,
+    invoice_item_id INT NOT NULL,
+    entry_specifier VARCHAR(36) NOT NULL,
+    item_id INT NOT NULL,
+    goods_received_note_id INT NOT NULL,
+    inventory_transfer_id INT,
+    qty INT NOT NULL,
+    cost_price DECIMAL(12, 2) NOT NULL,
+    price DECIMAL(12, 2) NOT NULL,
+    FOREIGN KEY (invoice_return_id) REFERENCES invoice_return(id),
+    FOREIGN KEY (invoice_item_id) REFERENCES invoice_item(id),
+    FOREIGN KEY (item_id) REFERENCES item(id)
+);
+
+ALTER TABLE invoice
+ADD COLUMN transaction_id INT,
+ADD COLUMN voided_by INT,
+ADD COLUMN voided_on DATETIME,
+ADD COLUMN void_reason TEXT,
+ADD COLUMN void_transaction_id INT,
+ADD FOREIGN KEY (transaction_id) REFERENCES transaction(id),
+ADD FOREIGN KEY (voided_by) REFERENCES user(id),
+ADD FOREIGN KEY (void_transaction_id) REFERENCES transaction(id);
+
+CREATE TABLE supplier_return (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    goods_received_note_id INT NOT NULL,
+    supplier_id INT NOT NULL,
+    warehouse_id INT NOT NULL,
+    created DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    total_price DECIMAL(12, 2) NOT NULL DEFAULT 0,
+    transaction_id INT,
+    remarks TEXT,
+    FOREIGN KEY (user_id) REFERENCES user(id),
+    FOREIGN KEY (goods_received_note_id) REFERENCES goods_received_note(id),
+    FOREIGN KEY (supplier_id) REFERENCES business_partner(id),
+    FOREIGN KEY (warehouse_id) REFERENCES business_partner(id),
+    FOREIGN KEY (transaction_id) REFERENCES transaction(id)
+);
+
+CREATE TABLE supplier_return_item (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    supplier_return_id INT NOT NULL,
+    entry_specifier VARCHAR(36) NOT NULL,
+    item_id INT NOT NULL,
+    qty INT NOT NULL,
+    cost_price DECIMAL(12, 2) NOT NULL,
+    FOREIGN KEY (supplier_return_id) REFERENCES supplier_return(id),
+    FOREIGN KEY (item_id) REFERENCES item(id)
+);
+
+CREATE TABLE stock_adjustment_reason (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(128) NOT NULL,
+    account_id INT,
+    FOREIGN KEY (account_id) REFERENCES account(id)
+);
+
+CREATE TABLE stock_adjustment (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    warehouse_id INT NOT NULL,
+    created DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    transaction_id INT,
+    remarks TEXT,
+    FOREIGN KEY (user_id) REFERENCES user(id),
+    FOREIGN KEY (warehouse_id) REFERENCES business_partner(id),
+    FOREIGN KEY (transaction_id) REFERENCES transaction(id)
+);
+
+CREATE TABLE stock_adjustment_item (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    stock_adjustment_id INT NOT NULL,
+    stock_adjustment_reason_id INT NOT NULL,
+    entry_specifier VARCHAR(36) NOT NULL,
+    item_id INT NOT NULL,
+    goods_received_note_id INT NOT NULL,
+    inventory_transfer_id INT,
+    qty INT NOT NULL,
+    cost_price DECIMAL(12, 2) NOT NULL,
+    FOREIGN KEY (stock_adjustment_id) REFERENCES stock_adjustment(id),
+    FOREIGN KEY (stock_adjustment_reason_id) REFERENCES stock_adjustment_reason(id),
+    FOREIGN KEY (item_id) REFERENCES item(id)
+);
+
+CREATE TABLE stock_take (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    warehouse_id INT NOT NULL,
+    created DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    status ENUM('Open', 'Approved', 'Cancelled') NOT NULL DEFAULT 'Open',
+    approved_by INT,
+    approved_on DATETIME,
+    stock_adjustment_id INT,
+    remarks TEXT,
+    FOREIGN KEY (user_id) REFERENCES user(id),
+    FOREIGN KEY (warehouse_id) REFERENCES business_partner(id),
+    FOREIGN KEY (approved_by) REFERENCES user(id),
+    FOREIGN KEY (stock_adjustment_id) REFERENCES stock_adjustment(id)
+);
+
+CREATE TABLE stock_take_item (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    stock_take_id INT NOT NULL,
+    item_id INT NOT NULL,
+    snapshot_qty INT NOT NULL,
+    FOREIGN KEY (stock_take_id) REFERENCES stock_take(id),
+    FOREIGN KEY (item_id) REFERENCES item(id)
+);
+
+CREATE TABLE stock_take_count (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    stock_take_id INT NOT NULL,
+    session INT NOT NULL,
+    item_id INT NOT NULL,
+    counted_qty INT NOT NULL,
+    user_id INT NOT NULL,
+    created DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (stock_take_id) REFERENCES stock_take(id),
+    FOREIGN KEY (item_id) REFERENCES item(id),
+    FOREIGN KEY (user_id) REFERENCES user(id)
+);
+
+CREATE TABLE item_reorder_level (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    item_id INT NOT NULL,
+    warehouse_id INT NOT NULL,
+    min_qty INT NOT NULL,
+    max_qty INT NOT NULL,
+    UNIQUE KEY (item_id, warehouse_id),
+    FOREIGN KEY (item_id) REFERENCES item(id),
+    FOREIGN KEY (warehouse_id) REFERENCES business_partner(id)
+);
+
+ALTER TABLE purchase_order
+ADD COLUMN closed_by INT NULL,
+ADD COLUMN closed_on DATETIME NULL,
+ADD COLUMN close_reason VARCHAR(512) NULL,
+ADD FOREIGN KEY (closed_by) REFERENCES user(id);
+
+CREATE TABLE purchase_order_cancellation (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    purchase_order_id INT NOT NULL,
+    purchase_order_item_id INT NOT NULL,
+    user_id INT NOT NULL,
+    created DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    qty DECIMAL(12, 2) NOT NULL,
+    reason VARCHAR(512) NOT NULL,
+    FOREIGN KEY (purchase_order_id) REFERENCES purchase_order(id),
+    FOREIGN KEY (purchase_order_item_id) REFERENCES purchase_order_item(id),
+    FOREIGN KEY (user_id) REFERENCES user(id)
+);
+
+ALTER TABLE purchase_order
+ADD COLUMN approval_status VARCHAR(16) NOT NULL DEFAULT 'Approved',
+ADD COLUMN approved_by INT NULL,
+ADD COLUMN approved_on DATETIME NULL,
+ADD FOREIGN KEY (approved_by) REFERENCES user(id);
+
+ALTER TABLE purchase_order
+ALTER COLUMN approval_status SET DEFAULT 'Draft';
+
+CREATE TABLE user_approval_limit (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL UNIQUE,
+    purchase_order_limit DECIMAL(12, 2) NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES user(id)
+);
+
+CREATE TABLE supplier_receipt_tolerance (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    supplier_id INT NOT NULL UNIQUE,
+    qty_tolerance DECIMAL(5, 2) NOT NULL DEFAULT 0,
+    price_tolerance DECIMAL(5, 2) NOT NULL DEFAULT 0,
+    action VARCHAR(16) NOT NULL DEFAULT 'Flag',
+    FOREIGN KEY (supplier_id) REFERENCES business_partner(id)
+);
+
+ALTER TABLE goods_received_note_item
+ADD COLUMN open_order_qty DECIMAL(12, 2) NULL,
+ADD COLUMN ordered_unit_price DECIMAL(12, 2) NULL,
+ADD COLUMN qty_variance DECIMAL(12, 2) NULL,
+ADD COLUMN price_variance DECIMAL(12, 2) NULL,
+ADD COLUMN variance_status VARCHAR(16) NULL,
+ADD COLUMN variance_resolved_by INT NULL,
+ADD COLUMN variance_resolved_on DATETIME NULL,
+ADD COLUMN variance_resolution VARCHAR(512) NULL,
+ADD FOREIGN KEY (variance_resolved_by) REFERENCES user(id);
+
+CREATE TABLE supplier_invoice (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    created DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    supplier_id INT NOT NULL,
+    invoice_number VARCHAR(64) NOT NULL,
+    invoice_date DATE NOT NULL,
+    due_date DATE NOT NULL,
+    total_amount DECIMAL(12, 2) NOT NULL,
+    grn_value DECIMAL(12, 2) NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    transaction_id INT NULL,
+    posted_by INT NULL,
+    posted_on DATETIME NULL,
+    remarks VARCHAR(512) NULL,
+    UNIQUE KEY (supplier_id, invoice_number),
+    FOREIGN KEY (user_id) REFERENCES user(id),
+    FOREIGN KEY (supplier_id) REFERENCES business_partner(id),
+    FOREIGN KEY (transaction_id) REFERENCES transaction(id),
+    FOREIGN KEY (posted_by) REFERENCES user(id)
+);
+
+CREATE TABLE supplier_invoice_item (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    supplier_invoice_id INT NOT NULL,
+    goods_received_note_id INT NOT NULL,
+    item_id INT NOT NULL,
+    qty DECIMAL(12, 2) NOT NULL,
+    unit_price DECIMAL(12, 2) NOT NULL,
+    total_price DECIMAL(12, 2) NOT NULL,
+    grn_unit_price DECIMAL(12, 2) NOT NULL,
+    order_unit_price DECIMAL(12, 2) NOT NULL,
+    qty_variance DECIMAL(12, 2) NOT NULL,
+    price_variance DECIMAL(12, 2) NOT NULL,
+    match_status VARCHAR(16) NOT NULL,
+    FOREIGN KEY (supplier_invoice_id) REFERENCES supplier_invoice(id),
+    FOREIGN KEY (goods_received_note_id) REFERENCES goods_received_note(id),
+    FOREIGN KEY (item_id) REFERENCES item(id)
+);
+
+ALTER TABLE landed_cost_type
+ADD COLUMN allocation_basis ENUM('Value', 'Quantity', 'Weight', 'Volume') NOT NULL DEFAULT 'Value';
+
+ALTER TABLE item
+ADD COLUMN weight DECIMAL(12, 3) NULL,
+ADD COLUMN volume DECIMAL(12, 3) NULL;
+
+ALTER TABLE landed_cost
+ADD COLUMN is_adjustment TINYINT(1) NOT NULL DEFAULT 0;
+
+CREATE TABLE landed_cost_goods_received_note (
+    landed_cost_id INT NOT NULL,
+    goods_received_note_id INT NOT NULL,
+    PRIMARY KEY (landed_cost_id, goods_received_note_id),
+    FOREIGN KEY (landed_cost_id) REFERENCES landed_cost(id),
+    FOREIGN KEY (goods_received_note_id) REFERENCES goods_received_note(id)
+);
+
+INSERT INTO landed_cost_goods_received_note (landed_cost_id, goods_received_note_id)
+SELECT id, goods_received_note_id FROM landed_cost WHERE is_adjustment = 0;
+
+ALTER TABLE purchase_order
+ADD COLUMN currency_code CHAR(3) NOT NULL DEFAULT 'LKR',
+ADD COLUMN exchange_rate DECIMAL(14, 6) NOT NULL DEFAULT 1;
+
+ALTER TABLE goods_received_note
+ADD COLUMN currency_code CHAR(3) NOT NULL DEFAULT 'LKR',
+ADD COLUMN exchange_rate DECIMAL(14, 6) NOT NULL DEFAULT 1;
+
+ALTER TABLE landed_cost_item
+ADD COLUMN foreign_amount DECIMAL(12, 2) NULL,
+ADD COLUMN currency_code CHAR(3) NOT NULL DEFAULT 'LKR',
+ADD COLUMN exchange_rate DECIMAL(14, 6) NOT NULL DEFAULT 1;
+
+ALTER TABLE supplier_invoice
+ADD COLUMN currency_code CHAR(3) NOT NULL DEFAULT 'LKR' AFTER due_date,
+ADD COLUMN exchange_rate DECIMAL(14, 6) NOT NULL DEFAULT 1 AFTER currency_code;
+
+ALTER TABLE business_partner_financial
+ADD COLUMN currency_code CHAR(3) NOT NULL DEFAULT 'LKR',
+ADD COLUMN foreign_amount DECIMAL(12, 2) NULL,
+ADD COLUMN exchange_rate DECIMAL(14, 6) NOT NULL DEFAULT 1;
+
+CREATE TABLE exchange_rate (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    created DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    currency_code CHAR(3) NOT NULL,
+    rate_date DATE NOT NULL,
+    rate DECIMAL(14, 6) NOT NULL,
+    UNIQUE KEY (currency_code, rate_date),
+    FOREIGN KEY (user_id) REFERENCES user(id)
+);
+
+CREATE TABLE exchange_revaluation (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    created DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    revaluation_date DATE NOT NULL UNIQUE,
+    transaction_id INT NULL,
+    reversal_transaction_id INT NULL,
+    FOREIGN KEY (user_id) REFERENCES user(id),
+    FOREIGN KEY (transaction_id) REFERENCES transaction(id),
+    FOREIGN KEY (reversal_transaction_id) REFERENCES transaction(id)
+);
+
+CREATE TABLE exchange_revaluation_item (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    exchange_revaluation_id INT NOT NULL,
+    business_partner_id INT NOT NULL,
+    currency_code CHAR(3) NOT NULL,
+    foreign_balance DECIMAL(12, 2) NOT NULL,
+    base_balance DECIMAL(12, 2) NOT NULL,
+    exchange_rate DECIMAL(14, 6) NOT NULL,
+    revalued_balance DECIMAL(12, 2) NOT NULL,
+    difference DECIMAL(12, 2) NOT NULL,
+    FOREIGN KEY (exchange_revaluation_id) REFERENCES exchange_revaluation(id),
+    FOREIGN KEY (business_partner_id) REFERENCES business_partner(id)
+);
+
+CREATE TABLE business_partner_payment_allocation (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    created DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    transaction_id INT NULL,
+    business_partner_id INT NOT NULL,
+    goods_received_note_id INT NULL,
+    currency_code CHAR(3) NOT NULL DEFAULT 'LKR',
+    amount DECIMAL(12, 2) NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES user(id),
+    FOREIGN KEY (transaction_id) REFERENCES transaction(id),
+    FOREIGN KEY (business_partner_id) REFERENCES business_partner(id),
+    FOREIGN KEY (goods_received_note_id) REFERENCES goods_received_note(id)
+);
+
+CREATE TABLE cheque (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    created DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    transaction_id INT NOT NULL,
+    cheque_number VARCHAR(32) NOT NULL,
+    account_id INT NOT NULL,
+    issue_date DATE NOT NULL,
+    effective_date DATE NOT NULL,
+    amount DECIMAL(12, 2) NOT NULL,
+    status ENUM('Issued', 'Presented', 'Cleared', 'Bounced', 'Cancelled') NOT NULL DEFAULT 'Issued',
+    status_changed_by INT NULL,
+    status_changed_on DATETIME NULL,
+    status_remarks TEXT NULL,
+    reversal_transaction_id INT NULL,
+    FOREIGN KEY (user_id) REFERENCES user(id),
+    FOREIGN KEY (transaction_id) REFERENCES transaction(id),
+    FOREIGN KEY (account_id) REFERENCES account(id),
+    FOREIGN KEY (status_changed_by) REFERENCES user(id),
+    FOREIGN KEY (reversal_transaction_id) REFERENCES transaction(id)
+);
+
+CREATE TABLE bank_statement (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    created DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    account_id INT NOT NULL,
+    file_name VARCHAR(255) NOT NULL,
+    statement_date DATE NOT NULL,
+    closing_balance DECIMAL(12, 2) NULL,
+    FOREIGN KEY (user_id) REFERENCES user(id),
+    FOREIGN KEY (account_id) REFERENCES account(id)
+);
+
+CREATE TABLE bank_statement_line (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    bank_statement_id INT NOT NULL,
+    account_id INT NOT NULL,
+    transaction_date DATE NOT NULL,
+    description VARCHAR(255) NULL,
+    reference VARCHAR(64) NULL,
+    amount DECIMAL(12, 2) NOT NULL,
+    account_transaction_id INT NULL UNIQUE,
+    match_type ENUM('Auto', 'Manual') NULL,
+    matched_by INT NULL,
+    matched_on DATETIME NULL,
+    FOREIGN KEY (bank_statement_id) REFERENCES bank_statement(id),
+    FOREIGN KEY (account_id) REFERENCES account(id),
+    FOREIGN KEY (account_transaction_id) REFERENCES account_transaction(id),
+    FOREIGN KEY (matched_by) REFERENCES user(id)
+);
+
+CREATE TABLE posting_rule (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NULL,
+    created DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    rule ENUM('Stock', 'Payable', 'Sales', 'CostOfSales', 'ExchangeGainLoss') NOT NULL,
+    item_category_id INT NULL,
+    warehouse_id INT NULL,
+    account_id INT NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES user(id),
+    FOREIGN KEY (item_category_id) REFERENCES item_category(id),
+    FOREIGN KEY (warehouse_id) REFERENCES business_partner(id),
+    FOREIGN KEY (account_id) REFERENCES account(id)
+);
+
+-- Accounts previously compiled in as constants. The exchange gain or loss
+-- account previously passed with -fxAccount must be added as a rule
+INSERT INTO posting_rule (rule, account_id) VALUES
+('Stock', 183),
+('Payable', 302),
+('Sales', 200),
+('CostOfSales', 202);
+
+CREATE TABLE financial_year (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NULL,
+    created DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    name VARCHAR(32) NOT NULL,
+    start_date DATE NOT NULL,
+    end_date DATE NOT NULL,
+    status ENUM('Open', 'Closed') NOT NULL DEFAULT 'Open',
+    FOREIGN KEY (user_id) REFERENCES user(id)
+);
+
+CREATE TABLE financial_period (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    financial_year_id INT NOT NULL,
+    name VARCHAR(32) NOT NULL,
+    start_date DATE NOT NULL,
+    end_date DATE NOT NULL,
+    status ENUM('Open', 'SoftClosed', 'Closed') NOT NULL DEFAULT 'Open',
+    status_changed_by INT NULL,
+    status_changed_on DATETIME NULL,
+    FOREIGN KEY (financial_year_id) REFERENCES financial_year(id),
+    FOREIGN KEY (status_changed_by) REFERENCES user(id)
+);
+
+ALTER TABLE user
+ADD COLUMN period_override TINYINT(1) NOT NULL DEFAULT 0;
+
+-- The April to March years previously hard-coded in validatePostingDate, from the
+-- year of the oldest posting up to the current year. Later years are created through
+-- /account/financialyear/new, a warning is logged at startup when none covers the date
+INSERT INTO financial_year (name, start_date, end_date)
+SELECT CONCAT(Y.y, '/', Y.y + 1), DATE_ADD(MAKEDATE(Y.y, 1), INTERVAL 3 MONTH), DATE_ADD(MAKEDATE(Y.y + 1, 1), INTERVAL 3 MONTH) - INTERVAL 1 DAY
+FROM (
+    SELECT R.first_year + T.n * 10 + U.n AS y, R.last_year
+    FROM (
+        SELECT YEAR(COALESCE(MIN(posting_date), CURDATE()) - INTERVAL 3 MONTH) AS first_year,
+        YEAR(GREATEST(COALESCE(MAX(posting_date), CURDATE()), CURDATE()) - INTERVAL 3 MONTH) AS last_year
+        FROM transaction
+    ) R
+    CROSS JOIN (SELECT 0 AS n UNION ALL SELECT 1 UNION ALL SELECT 2 UNION ALL SELECT 3 UNION ALL SELECT 4
+        UNION ALL SELECT 5 UNION ALL SELECT 6 UNION ALL SELECT 7 UNION ALL SELECT 8 UNION ALL SELECT 9) T
+    CROSS JOIN (SELECT 0 AS n UNION ALL SELECT 1 UNION ALL SELECT 2 UNION ALL SELECT 3 UNION ALL SELECT 4
+        UNION ALL SELECT 5 UNION ALL SELECT 6 UNION ALL SELECT 7 UNION ALL SELECT 8 UNION ALL SELECT 9) U
+) Y
+WHERE Y.y <= Y.last_year
+ORDER BY Y.y;
+INSERT INTO financial_period (financial_year_id, name, start_date, end_date)
+SELECT FY.id, DATE_FORMAT(DATE_ADD(FY.start_date, INTERVAL M.n MONTH), '%b %Y'), DATE_ADD(FY.start_date, INTERVAL M.n MONTH), LAST_DAY(DATE_ADD(FY.start_date, INTERVAL M.n MONTH))
+FROM financial_year FY
+CROSS JOIN (SELECT 0 AS n UNION ALL SELECT 1 UNION ALL SELECT 2 UNION ALL SELECT 3 UNION ALL SELECT 4 UNION ALL SELECT 5
+    UNION ALL SELECT 6 UNION ALL SELECT 7 UNION ALL SELECT 8 UNION ALL SELECT 9 UNION ALL SELECT 10 UNION ALL SELECT 11) M
+ORDER BY FY.start_date, M.n;
+
+ALTER TABLE posting_rule
+MODIFY COLUMN rule ENUM('Stock', 'Payable', 'Sales', 'CostOfSales', 'ExchangeGainLoss', 'RetainedEarnings') NOT NULL;
+
+CREATE TABLE financial_year_closing (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    financial_year_id INT NOT NULL,
+    user_id INT NOT NULL,
+    created DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    net_profit DECIMAL(15, 2) NOT NULL,
+    transaction_id INT NULL,
+    reopened_by INT NULL,
+    reopened_on DATETIME NULL,
+    reopen_remarks VARCHAR(256) NULL,
+    reversal_transaction_id INT NULL,
+    FOREIGN KEY (financial_year_id) REFERENCES financial_year(id),
+    FOREIGN KEY (user_id) REFERENCES user(id),
+    FOREIGN KEY (transaction_id) REFERENCES transaction(id),
+    FOREIGN KEY (reopened_by) REFERENCES user(id),
+    FOREIGN KEY (reversal_transaction_id) REFERENCES transaction(id)
+);
+
+ALTER TABLE stock_take
+ADD COLUMN cancelled_by INT NULL,
+ADD COLUMN cancelled_on DATETIME NULL,
+ADD COLUMN cancel_remarks TEXT NULL,
+ADD FOREIGN KEY (cancelled_by) REFERENCES user(id);
+
+-- Rejected supplier invoices release their invoice number for the corrected invoice
+ALTER TABLE supplier_invoice
+ADD COLUMN rejected_by INT NULL,
+ADD COLUMN rejected_on DATETIME NULL,
+ADD COLUMN reject_remarks VARCHAR(512) NULL,
+ADD COLUMN active_invoice_number VARCHAR(64) AS (IF(status = 'Rejected', NULL, invoice_number)) STORED,
+ADD FOREIGN KEY (rejected_by) REFERENCES user(id),
+ADD UNIQUE KEY supplier_active_invoice_number (supplier_id, active_invoice_number),
+DROP INDEX supplier_id;
+
+ALTER TABLE user_approval_limit
+ADD COLUMN supplier_invoice_limit DECIMAL(12, 2) NOT NULL DEFAULT 0;
+
+ALTER TABLE supplier_invoice
+ADD COLUMN approved_by INT NULL,
+ADD COLUMN approved_on DATETIME NULL,
+ADD COLUMN approval_remarks VARCHAR(512) NULL,
+ADD FOREIGN KEY (approved_by) REFERENCES user(id);
+
+-- Changes to the unit cost of current stock entries after they were received so that
+-- stock can be valued at the cost it had on an earlier date
+CREATE TABLE current_stock_cost_change (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    created DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    entry_specifier VARCHAR(36) NOT NULL,
+    cost_price DECIMAL(14, 4) NOT NULL,
+    landed_costs DECIMAL(14, 4) NOT NULL,
+    price DECIMAL(14, 4) NOT NULL,
+    INDEX (entry_specifier)
+);
@@ -57,7 +57,7 @@ func (app *application) authenticate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user := models.UserResponse{u.ID, u.Username, u.Name, u.Type, ts, u.WarehouseID, u.WarehouseName}
+	user := models.UserResponse{ID: u.ID, Username: u.Username, Name: u.Name, Role: u.Type, Token: ts, WarehouseID: u.WarehouseID, WarehouseName: u.WarehouseName}
 	js, err := json.Marshal(user)
 	if err != nil {
 		app.serverError(w, err)
@@ -157,6 +157,19 @@ func (app *application) dropdownGrnHandler(w http.ResponseWriter, _ *http.Reques
 
 }
 
+func (app *application) dropdownCostedGrnHandler(w http.ResponseWriter, _ *http.Request) {
+
+	items, err := app.dropdown.GetCostedGrn()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(items)
+
+}
+
 func (app *application) itemTest(w http.ResponseWriter, _ *http.Request) {
 	fmt.Fprintf(w, "Item Test")
 }
@@ -214,6 +227,36 @@ func (app *application) itemStock(w http.ResponseWriter, r *http.Request) {
 
 }
 
+func (app *application) itemMovements(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	if id == "" {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	startDate := r.URL.Query().Get("startdate")
+	endDate := r.URL.Query().Get("enddate")
+	for _, date := range []string{startDate, endDate} {
+		if date == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			app.clientError(w, http.StatusBadRequest)
+			return
+		}
+	}
+
+	movements, err := app.item.Movements(id, r.URL.Query().Get("warehouse"), startDate, endDate)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(movements)
+}
+
 func (app *application) itemDetails(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
@@ -259,6 +302,54 @@ func (app *application) updateItemById(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprintf(w, "%d", id)
 }
 
+func (app *application) setItemReorderLevel(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	requiredParams := []string{"item_id", "warehouse_id", "min_qty", "max_qty"}
+	for _, param := range requiredParams {
+		if v := r.PostForm.Get(param); v == "" {
+			app.clientError(w, http.StatusBadRequest)
+			return
+		}
+	}
+
+	id, err := app.item.SetReorderLevel(r.PostForm)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	fmt.Fprintf(w, "%d", id)
+}
+
+func (app *application) setReceiptTolerance(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	requiredParams := []string{"supplier_id", "qty_tolerance", "price_tolerance", "action"}
+	for _, param := range requiredParams {
+		if v := r.PostForm.Get(param); v == "" {
+			app.clientError(w, http.StatusBadRequest)
+			return
+		}
+	}
+
+	id, err := app.businessPartner.SetReceiptTolerance(r.PostForm)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	fmt.Fprintf(w, "%d", id)
+}
+
 func (app *application) createBusinessPartner(w http.ResponseWriter, r *http.Request) {
 	err := r.ParseForm()
 	if err != nil {
@@ -297,6 +388,75 @@ func (app *application) businessPartnerBalances(w http.ResponseWriter, _ *http.R
 	_ = json.NewEncoder(w).Encode(results)
 }
 
+func (app *application) businessPartnerAging(w http.ResponseWriter, r *http.Request) {
+	date := r.URL.Query().Get("date")
+	if date == "" {
+		date = time.Now().Format("2006-01-02")
+	}
+
+	_, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	results, err := app.businessPartner.Aging(date)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	if r.URL.Query().Get("format") == "csv" {
+		records := [][]string{{"id", "name", "current", "1-30", "31-60", "61-90", "90+", "balance"}}
+		for _, a := range results {
+			records = append(records, []string{strconv.Itoa(a.ID), a.Name, fmt.Sprintf("%.2f", a.Current), fmt.Sprintf("%.2f", a.Days1To30), fmt.Sprintf("%.2f", a.Days31To60), fmt.Sprintf("%.2f", a.Days61To90), fmt.Sprintf("%.2f", a.Over90), fmt.Sprintf("%.2f", a.Balance)})
+		}
+		app.writeCSV(w, fmt.Sprintf("aging-%s.csv", date), records)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(results)
+}
+
+func (app *application) businessPartnerAgingDetail(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	bpid, err := strconv.Atoi(vars["bpid"])
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	date := r.URL.Query().Get("date")
+	if date == "" {
+		date = time.Now().Format("2006-01-02")
+	}
+
+	_, err = time.Parse("2006-01-02", date)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	items, err := app.businessPartner.AgingDetail(bpid, date)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	if r.URL.Query().Get("format") == "csv" {
+		records := [][]string{{"grn_id", "transaction_id", "effective_date", "currency_code", "amount", "foreign_open_amount", "open_amount", "days", "bucket", "remark"}}
+		for _, i := range items {
+			records = append(records, []string{strconv.Itoa(i.GRNID), strconv.Itoa(i.TransactionID), i.EffectiveDate, i.CurrencyCode, fmt.Sprintf("%.2f", i.Amount), fmt.Sprintf("%.2f", i.ForeignOpenAmount), fmt.Sprintf("%.2f", i.OpenAmount), strconv.Itoa(i.Days), i.Bucket, i.Remark})
+		}
+		app.writeCSV(w, fmt.Sprintf("aging-%d-%s.csv", bpid, date), records)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(items)
+}
+
 func (app *application) createItem(w http.ResponseWriter, r *http.Request) {
 	err := r.ParseForm()
 	if err != nil {
@@ -305,7 +465,7 @@ func (app *application) createItem(w http.ResponseWriter, r *http.Request) {
 	}
 
 	requiredParams := []string{"user_id", "item_id", "model_id", "item_category_id", "page_no", "item_no", "foreign_id", "name", "price"}
-	optionalParams := []string{}
+	optionalParams := []string{"weight", "volume"}
 	for _, param := range requiredParams {
 		if v := r.PostForm.Get(param); v == "" {
 			fmt.Println(param)
@@ -415,6 +575,10 @@ func (app *application) accountDeposit(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !app.setAuthenticatedUser(w, r) {
+		return
+	}
+
 	requiredParams := []string{"user_id", "posting_date", "to_account_id", "amount", "entries", "remark"}
 	for _, param := range requiredParams {
 		if v := r.PostForm.Get(param); v == "" {
@@ -424,7 +588,7 @@ func (app *application) accountDeposit(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	tid, err := app.account.Deposit(r.PostForm.Get("user_id"), r.PostForm.Get("posting_date"), r.PostForm.Get("to_account_id"), r.PostForm.Get("amount"), r.PostForm.Get("entries"), r.PostForm.Get("remark"))
+	tid, err := app.accountPosting.Deposit(r.PostForm.Get("user_id"), r.PostForm.Get("posting_date"), r.PostForm.Get("to_account_id"), r.PostForm.Get("amount"), r.PostForm.Get("entries"), r.PostForm.Get("remark"))
 	if err != nil {
 		app.serverError(w, err)
 		return
@@ -440,6 +604,10 @@ func (app *application) accountJournalEntry(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	if !app.setAuthenticatedUser(w, r) {
+		return
+	}
+
 	requiredParams := []string{"user_id", "posting_date", "remark", "entries"}
 	for _, param := range requiredParams {
 		if v := r.PostForm.Get(param); v == "" {
@@ -449,7 +617,7 @@ func (app *application) accountJournalEntry(w http.ResponseWriter, r *http.Reque
 		}
 	}
 
-	tid, err := app.account.JournalEntry(r.PostForm.Get("user_id"), r.PostForm.Get("posting_date"), r.PostForm.Get("remark"), r.PostForm.Get("entries"))
+	tid, err := app.accountPosting.JournalEntry(r.PostForm.Get("user_id"), r.PostForm.Get("posting_date"), r.PostForm.Get("remark"), r.PostForm.Get("entries"))
 	if err != nil {
 		app.serverError(w, err)
 		return
@@ -465,6 +633,10 @@ func (app *application) businessPartnerPayment(w http.ResponseWriter, r *http.Re
 		return
 	}
 
+	if !app.setAuthenticatedUser(w, r) {
+		return
+	}
+
 	requiredParams := []string{"user_id", "posting_date", "effective_date", "from_account_id", "amount", "entries", "remark"}
 	for _, param := range requiredParams {
 		if v := r.PostForm.Get(param); v == "" {
@@ -475,235 +647,263 @@ func (app *application) businessPartnerPayment(w http.ResponseWriter, r *http.Re
 	}
 
 	tid, err := app.businessPartner.Payment(r.PostForm.Get("user_id"), r.PostForm.Get("posting_date"), r.PostForm.Get("from_account_id"), r.PostForm.Get("amount"), r.PostForm.Get("entries"), r.PostForm.Get("remark"), r.PostForm.Get("effective_date"), r.PostForm.Get("check_number"))
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
 
 	fmt.Fprintf(w, "%v", tid)
 }
 
-func (app *application) accountPaymentVoucher(w http.ResponseWriter, r *http.Request) {
+func (app *application) allocateBusinessPartnerPayment(w http.ResponseWriter, r *http.Request) {
 	err := r.ParseForm()
 	if err != nil {
 		app.clientError(w, http.StatusBadRequest)
 		return
 	}
 
-	requiredParams := []string{"user_id", "posting_date", "from_account_id", "amount", "entries", "remark"}
+	requiredParams := []string{"user_id", "business_partner_id", "allocations"}
 	for _, param := range requiredParams {
 		if v := r.PostForm.Get(param); v == "" {
-			fmt.Println(param)
 			app.clientError(w, http.StatusBadRequest)
 			return
 		}
 	}
 
-	tid, err := app.account.PaymentVoucher(r.PostForm.Get("user_id"), r.PostForm.Get("posting_date"), r.PostForm.Get("from_account_id"), r.PostForm.Get("amount"), r.PostForm.Get("entries"), r.PostForm.Get("remark"), r.PostForm.Get("due_date"), r.PostForm.Get("check_number"), r.PostForm.Get("payee"))
+	id, err := app.businessPartner.AllocatePayment(r.PostForm)
 	if err != nil {
 		app.serverError(w, err)
 		return
 	}
 
-	fmt.Fprintf(w, "%v", tid)
+	fmt.Fprintf(w, "%d", id)
 }
 
-func (app *application) accountLedger(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	aid, err := strconv.Atoi(vars["aid"])
+func (app *application) updateChequeStatus(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
 	if err != nil {
 		app.clientError(w, http.StatusBadRequest)
 		return
 	}
 
-	ledger, err := app.account.Ledger(aid)
-	if err != nil {
-		app.serverError(w, err)
+	if !app.setAuthenticatedUser(w, r) {
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(ledger)
-}
+	requiredParams := []string{"user_id", "cheque_id", "status"}
+	for _, param := range requiredParams {
+		if v := r.PostForm.Get(param); v == "" {
+			app.clientError(w, http.StatusBadRequest)
+			return
+		}
+	}
 
-func (app *application) bpBalanceDetail(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	bpid, err := strconv.Atoi(vars["bpid"])
+	id, err := app.cheque.UpdateStatus(r.PostForm)
 	if err != nil {
-		app.clientError(w, http.StatusBadRequest)
+		app.serverError(w, err)
 		return
 	}
 
-	ledger, err := app.businessPartner.BPBalanceDetail(bpid)
+	fmt.Fprintf(w, "%d", id)
+}
+
+func (app *application) chequeList(w http.ResponseWriter, r *http.Request) {
+	cheques, err := app.cheque.List(r.URL.Query().Get("status"))
 	if err != nil {
 		app.serverError(w, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(ledger)
+	_ = json.NewEncoder(w).Encode(cheques)
 }
 
-func (app *application) accountChart(w http.ResponseWriter, _ *http.Request) {
-	accounts, err := app.account.ChartOfAccounts()
+func (app *application) chequesMaturing(w http.ResponseWriter, r *http.Request) {
+	days := 7
+	if d := r.URL.Query().Get("days"); d != "" {
+		var err error
+		days, err = strconv.Atoi(d)
+		if err != nil || days < 0 {
+			app.clientError(w, http.StatusBadRequest)
+			return
+		}
+	}
+
+	cheques, err := app.cheque.Maturing(days)
 	if err != nil {
 		app.serverError(w, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(accounts)
+	_ = json.NewEncoder(w).Encode(cheques)
 }
 
-func (app *application) paymentVouchers(w http.ResponseWriter, _ *http.Request) {
-	items, err := app.account.PaymentVouchers()
+func (app *application) importBankStatement(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseMultipartForm(10 << 20)
 	if err != nil {
 		app.clientError(w, http.StatusBadRequest)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(items)
-
-}
-
-func (app *application) paymentVoucherDetails(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	pid, err := strconv.Atoi(vars["pid"])
-	if err != nil {
+	userID := r.FormValue("user_id")
+	accountID := r.FormValue("account_id")
+	if userID == "" || accountID == "" {
 		app.clientError(w, http.StatusBadRequest)
 		return
 	}
 
-	items, err := app.account.PaymentVoucherDetails(pid)
+	file, header, err := r.FormFile("file")
 	if err != nil {
 		app.clientError(w, http.StatusBadRequest)
 		return
 	}
+	defer file.Close()
 
-	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(items)
+	sid, err := app.bankRec.ImportStatement(userID, accountID, header.Filename, file)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
 
+	fmt.Fprintf(w, "%d", sid)
 }
 
-func (app *application) accountTransaction(w http.ResponseWriter, r *http.Request) {
+func (app *application) bankStatement(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
-	tid, err := strconv.Atoi(vars["tid"])
+	sid, err := strconv.Atoi(vars["sid"])
 	if err != nil {
 		app.clientError(w, http.StatusBadRequest)
 		return
 	}
 
-	ledger, err := app.account.Transaction(tid)
+	lines, err := app.bankRec.Statement(sid)
 	if err != nil {
 		app.serverError(w, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(ledger)
+	_ = json.NewEncoder(w).Encode(lines)
 }
 
-func (app *application) accountTrialBalance(w http.ResponseWriter, r *http.Request) {
-	postingdate := r.URL.Query().Get("postingdate")
-
-	accounts, err := app.account.TrialBalance(postingdate)
+func (app *application) matchBankStatementLine(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
 	if err != nil {
-		app.serverError(w, err)
+		app.clientError(w, http.StatusBadRequest)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(accounts)
-}
-
-func (app *application) journalEntryAudit(w http.ResponseWriter, r *http.Request) {
-	entrydate := r.URL.Query().Get("entrydate")
-	postingdate := r.URL.Query().Get("postingdate")
+	requiredParams := []string{"user_id", "line_id", "account_transaction_id"}
+	for _, param := range requiredParams {
+		if v := r.PostForm.Get(param); v == "" {
+			app.clientError(w, http.StatusBadRequest)
+			return
+		}
+	}
 
-	results, err := app.account.JournalEntriesForAudit(entrydate, postingdate)
+	id, err := app.bankRec.Match(r.PostForm)
 	if err != nil {
 		app.serverError(w, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(results)
+	fmt.Fprintf(w, "%d", id)
 }
 
-func (app *application) pnlSummary(w http.ResponseWriter, r *http.Request) {
-	startDate := r.URL.Query().Get("startdate")
-	endDate := r.URL.Query().Get("enddate")
-
-	results, err := app.account.AccountsForPNL(startDate, endDate)
+func (app *application) unmatchBankStatementLine(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
 	if err != nil {
-		app.serverError(w, err)
+		app.clientError(w, http.StatusBadRequest)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(results)
-}
-
-func (app *application) balanceSheetSummary(w http.ResponseWriter, r *http.Request) {
-	postingdate := r.URL.Query().Get("postingdate")
+	requiredParams := []string{"user_id", "line_id"}
+	for _, param := range requiredParams {
+		if v := r.PostForm.Get(param); v == "" {
+			app.clientError(w, http.StatusBadRequest)
+			return
+		}
+	}
 
-	results, err := app.account.BalanceSheetSummary(postingdate)
+	id, err := app.bankRec.Unmatch(r.PostForm)
 	if err != nil {
 		app.serverError(w, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(results)
+	fmt.Fprintf(w, "%d", id)
 }
 
-func (app *application) accountBalancesForReporting(w http.ResponseWriter, r *http.Request) {
-	postingdate := r.URL.Query().Get("postingdate")
+func (app *application) bankReconciliation(w http.ResponseWriter, r *http.Request) {
+	accountID, err := strconv.Atoi(r.URL.Query().Get("account_id"))
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
 
-	results, err := app.account.AccountBalancesForReporting(postingdate)
+	date := r.URL.Query().Get("date")
+	if date == "" {
+		date = time.Now().Format("2006-01-02")
+	}
+
+	rec, err := app.bankRec.Reconciliation(accountID, date)
 	if err != nil {
 		app.serverError(w, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(results)
+	_ = json.NewEncoder(w).Encode(rec)
 }
 
-func (app *application) invoiceSearch(w http.ResponseWriter, r *http.Request) {
-	startDate := r.URL.Query().Get("startdate")
-	_, err := time.Parse("2006-01-02", startDate)
+func (app *application) setPostingRule(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
 	if err != nil {
 		app.clientError(w, http.StatusBadRequest)
 		return
 	}
 
-	endDate := r.URL.Query().Get("enddate")
-	_, err = time.Parse("2006-01-02", endDate)
+	requiredParams := []string{"user_id", "rule", "account_id"}
+	for _, param := range requiredParams {
+		if v := r.PostForm.Get(param); v == "" {
+			app.clientError(w, http.StatusBadRequest)
+			return
+		}
+	}
+
+	id, err := app.postingRule.Set(r.PostForm)
 	if err != nil {
-		app.clientError(w, http.StatusBadRequest)
+		app.serverError(w, err)
 		return
 	}
 
-	officer := r.URL.Query().Get("officer")
+	fmt.Fprintf(w, "%d", id)
+}
 
-	results, err := app.reporting.InvoiceSearch(startDate, endDate, officer)
+func (app *application) postingRuleList(w http.ResponseWriter, _ *http.Request) {
+	rules, err := app.postingRule.List()
 	if err != nil {
 		app.serverError(w, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(results)
+	_ = json.NewEncoder(w).Encode(rules)
 }
 
-func (app *application) createInvoice(w http.ResponseWriter, r *http.Request) {
+func (app *application) createFinancialYear(w http.ResponseWriter, r *http.Request) {
 	err := r.ParseForm()
 	if err != nil {
 		app.clientError(w, http.StatusBadRequest)
 		return
 	}
 
-	requiredParams := []string{"user_id", "from_warehouse", "customer_contact", "discount", "items", "request_id"}
-	optionalParams := []string{}
+	if !app.setAuthenticatedUser(w, r) {
+		return
+	}
 
+	requiredParams := []string{"user_id", "start_date"}
 	for _, param := range requiredParams {
 		if v := r.PostForm.Get(param); v == "" {
 			app.clientError(w, http.StatusBadRequest)
@@ -711,7 +911,7 @@ func (app *application) createInvoice(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	id, err := app.transactions.CreateInvoice(requiredParams, optionalParams, app.fgAPIKey, r.PostForm)
+	id, err := app.financialPeriod.CreateYear(r.PostForm)
 	if err != nil {
 		app.serverError(w, err)
 		return
@@ -720,15 +920,18 @@ func (app *application) createInvoice(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprintf(w, "%d", id)
 }
 
-func (app *application) inventoryTransferAction(w http.ResponseWriter, r *http.Request) {
+func (app *application) setFinancialPeriodStatus(w http.ResponseWriter, r *http.Request) {
 	err := r.ParseForm()
 	if err != nil {
 		app.clientError(w, http.StatusBadRequest)
 		return
 	}
 
-	requiredParams := []string{"inventory_transfer_id", "user_id", "resolution", "resolution_remarks", "request_id"}
-	optionalParams := []string{}
+	if !app.setAuthenticatedUser(w, r) {
+		return
+	}
+
+	requiredParams := []string{"user_id", "period_id", "status"}
 	for _, param := range requiredParams {
 		if v := r.PostForm.Get(param); v == "" {
 			app.clientError(w, http.StatusBadRequest)
@@ -736,7 +939,7 @@ func (app *application) inventoryTransferAction(w http.ResponseWriter, r *http.R
 		}
 	}
 
-	id, err := app.transactions.InventoryTransferAction(requiredParams, optionalParams, r.PostForm)
+	id, err := app.financialPeriod.SetPeriodStatus(r.PostForm)
 	if err != nil {
 		app.serverError(w, err)
 		return
@@ -745,40 +948,68 @@ func (app *application) inventoryTransferAction(w http.ResponseWriter, r *http.R
 	fmt.Fprintf(w, "%d", id)
 }
 
-func (app *application) createInventoryTransfer(w http.ResponseWriter, r *http.Request) {
-	err := r.ParseForm()
+func (app *application) financialYears(w http.ResponseWriter, _ *http.Request) {
+	years, err := app.financialPeriod.Years()
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(years)
+}
+
+func (app *application) financialPeriods(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	fyid, err := strconv.Atoi(vars["fyid"])
 	if err != nil {
 		app.clientError(w, http.StatusBadRequest)
 		return
 	}
 
-	requiredParams := []string{"user_id", "from_warehouse_id", "to_warehouse_id", "entries"}
-	optionalParams := []string{"remark"}
-	for _, param := range requiredParams {
-		if v := r.PostForm.Get(param); v == "" {
-			app.clientError(w, http.StatusBadRequest)
-			return
-		}
+	periods, err := app.financialPeriod.Periods(fyid)
+	if err != nil {
+		app.serverError(w, err)
+		return
 	}
 
-	id, err := app.transactions.CreateInventoryTransfer(requiredParams, optionalParams, r.PostForm)
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(periods)
+}
+
+func (app *application) financialYearClosingPreview(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	fyid, err := strconv.Atoi(vars["fyid"])
 	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	closing, err := app.financialPeriod.ClosingPreview(fyid)
+	if errors.Is(err, models.ErrNoRecord) {
+		app.notFound(w)
+		return
+	} else if err != nil {
 		app.serverError(w, err)
 		return
 	}
 
-	fmt.Fprintf(w, "%d", id)
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(closing)
 }
 
-func (app *application) createOrder(w http.ResponseWriter, r *http.Request) {
+func (app *application) closeFinancialYear(w http.ResponseWriter, r *http.Request) {
 	err := r.ParseForm()
 	if err != nil {
 		app.clientError(w, http.StatusBadRequest)
 		return
 	}
 
-	requiredParams := []string{"supplier_id", "warehouse_id", "entries"}
-	optionalParams := []string{"remark"}
+	if !app.setAuthenticatedUser(w, r) {
+		return
+	}
+
+	requiredParams := []string{"user_id", "financial_year_id"}
 	for _, param := range requiredParams {
 		if v := r.PostForm.Get(param); v == "" {
 			app.clientError(w, http.StatusBadRequest)
@@ -786,7 +1017,7 @@ func (app *application) createOrder(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	id, err := app.purchaseOrder.CreatePurchaseOrder(requiredParams, optionalParams, r.PostForm)
+	id, err := app.financialPeriod.CloseYear(r.PostForm)
 	if err != nil {
 		app.serverError(w, err)
 		return
@@ -795,243 +1026,287 @@ func (app *application) createOrder(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprintf(w, "%d", id)
 }
 
-func (app *application) getPendingInventoryTransfers(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	userType := vars["type"]
-	wid, err := strconv.Atoi(vars["warehouse"])
+func (app *application) reopenFinancialYear(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
 	if err != nil {
 		app.clientError(w, http.StatusBadRequest)
 		return
 	}
 
-	pendingTransfers, err := app.transactions.GetPendingTransfers(wid, userType)
+	if !app.setAuthenticatedUser(w, r) {
+		return
+	}
+
+	requiredParams := []string{"user_id", "financial_year_id", "remarks"}
+	for _, param := range requiredParams {
+		if v := r.PostForm.Get(param); v == "" {
+			app.clientError(w, http.StatusBadRequest)
+			return
+		}
+	}
 
+	id, err := app.financialPeriod.ReopenYear(r.PostForm)
 	if err != nil {
-		app.clientError(w, http.StatusBadRequest)
+		app.serverError(w, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(pendingTransfers)
+	fmt.Fprintf(w, "%d", id)
 }
 
-func (app *application) salesCommission(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	uid, err := strconv.Atoi(vars["uid"])
+func (app *application) createExchangeRate(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
 	if err != nil {
 		app.clientError(w, http.StatusBadRequest)
 		return
 	}
 
-	inventoryTransferItems, err := app.transactions.GetSalesCommission(uid)
+	requiredParams := []string{"user_id", "currency_code", "rate_date", "rate"}
+	for _, param := range requiredParams {
+		if v := r.PostForm.Get(param); v == "" {
+			app.clientError(w, http.StatusBadRequest)
+			return
+		}
+	}
 
+	id, err := app.exchangeRate.CreateRate(r.PostForm)
 	if err != nil {
-		app.clientError(w, http.StatusBadRequest)
+		app.serverError(w, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(inventoryTransferItems)
+	fmt.Fprintf(w, "%d", id)
 }
 
-func (app *application) cashInHand(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	uid, err := strconv.Atoi(vars["uid"])
+func (app *application) importExchangeRates(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseMultipartForm(10 << 20)
 	if err != nil {
 		app.clientError(w, http.StatusBadRequest)
 		return
 	}
 
-	inventoryTransferItems, err := app.transactions.GetCashInHand(uid)
+	userID := r.FormValue("user_id")
+	if userID == "" {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
 
+	file, _, err := r.FormFile("file")
 	if err != nil {
 		app.clientError(w, http.StatusBadRequest)
 		return
 	}
+	defer file.Close()
 
-	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(inventoryTransferItems)
-}
-
-func (app *application) inventoryTransferItems(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	itid, err := strconv.Atoi(vars["itid"])
+	count, err := app.exchangeRate.ImportRates(userID, file)
 	if err != nil {
-		app.clientError(w, http.StatusBadRequest)
+		app.serverError(w, err)
 		return
 	}
 
-	inventoryTransferItems, err := app.transactions.GetInventoryTransferItems(itid)
+	fmt.Fprintf(w, "%d", count)
+}
 
+func (app *application) exchangeRateList(w http.ResponseWriter, r *http.Request) {
+	rates, err := app.exchangeRate.List(r.URL.Query().Get("currency"))
 	if err != nil {
-		app.clientError(w, http.StatusBadRequest)
+		app.serverError(w, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(inventoryTransferItems)
+	_ = json.NewEncoder(w).Encode(rates)
 }
 
-func (app *application) getWarehouseStock(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	wid, err := strconv.Atoi(vars["wid"])
+func (app *application) exchangeRevaluation(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
 	if err != nil {
 		app.clientError(w, http.StatusBadRequest)
 		return
 	}
 
-	goodsReceivedNote, err := app.transactions.GetWarehouseStock(wid)
-
-	if err != nil {
-		app.clientError(w, http.StatusBadRequest)
+	if !app.setAuthenticatedUser(w, r) {
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(goodsReceivedNote)
-}
+	requiredParams := []string{"user_id", "date"}
+	for _, param := range requiredParams {
+		if v := r.PostForm.Get(param); v == "" {
+			app.clientError(w, http.StatusBadRequest)
+			return
+		}
+	}
 
-func (app *application) purchaseOrderList(w http.ResponseWriter, _ *http.Request) {
-	orders, err := app.purchaseOrder.PurchaseOrderList()
+	id, err := app.exchangeRate.Revalue(r.PostForm)
 	if err != nil {
 		app.serverError(w, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(orders)
+	fmt.Fprintf(w, "%d", id)
 }
 
-func (app *application) purchaseOrderDetails(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	pid, err := strconv.Atoi(vars["pid"])
-
-	if err != nil {
-		app.clientError(w, http.StatusBadRequest)
-		return
+func (app *application) businessPartnerForeignBalances(w http.ResponseWriter, r *http.Request) {
+	date := r.URL.Query().Get("date")
+	if date == "" {
+		date = time.Now().Format("2006-01-02")
 	}
 
-	purchaseOrder, err := app.purchaseOrder.PurchaseOrderDetails(pid)
-
+	balances, err := app.businessPartner.ForeignBalances(date)
 	if err != nil {
-		app.clientError(w, http.StatusBadRequest)
+		app.serverError(w, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(purchaseOrder)
-
+	_ = json.NewEncoder(w).Encode(balances)
 }
 
-func (app *application) createGoodsReceivedNote(w http.ResponseWriter, r *http.Request) {
+func (app *application) accountPaymentVoucher(w http.ResponseWriter, r *http.Request) {
 	err := r.ParseForm()
 	if err != nil {
 		app.clientError(w, http.StatusBadRequest)
 		return
 	}
 
-	requiredParams := []string{"supplier_id", "warehouse_id", "effective_date", "entries"}
-	optionalParams := []string{"remark"}
+	if !app.setAuthenticatedUser(w, r) {
+		return
+	}
+
+	requiredParams := []string{"user_id", "posting_date", "from_account_id", "amount", "entries", "remark"}
 	for _, param := range requiredParams {
 		if v := r.PostForm.Get(param); v == "" {
+			fmt.Println(param)
 			app.clientError(w, http.StatusBadRequest)
 			return
 		}
 	}
 
-	id, err := app.goodsReceivedNote.CreateGoodsReceivedNote(requiredParams, optionalParams, r.PostForm)
+	tid, err := app.accountPosting.PaymentVoucher(r.PostForm.Get("user_id"), r.PostForm.Get("posting_date"), r.PostForm.Get("from_account_id"), r.PostForm.Get("amount"), r.PostForm.Get("entries"), r.PostForm.Get("remark"), r.PostForm.Get("due_date"), r.PostForm.Get("check_number"), r.PostForm.Get("payee"))
 	if err != nil {
 		app.serverError(w, err)
 		return
 	}
 
-	fmt.Fprintf(w, "%d", id)
+	fmt.Fprintf(w, "%v", tid)
 }
 
-func (app *application) inventoryTransferList(w http.ResponseWriter, r *http.Request) {
-	notes, err := app.transactions.InventoryTransferList()
+func (app *application) accountLedger(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	aid, err := strconv.Atoi(vars["aid"])
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	ledger, err := app.account.Ledger(aid)
 	if err != nil {
 		app.serverError(w, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(notes)
+	_ = json.NewEncoder(w).Encode(ledger)
 }
 
-func (app *application) goodsReceivedNoteList(w http.ResponseWriter, r *http.Request) {
-	notes, err := app.goodsReceivedNote.GoodsReceivedNotesList()
+func (app *application) bpBalanceDetail(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	bpid, err := strconv.Atoi(vars["bpid"])
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	ledger, err := app.businessPartner.BPBalanceDetail(bpid)
 	if err != nil {
 		app.serverError(w, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(notes)
+	_ = json.NewEncoder(w).Encode(ledger)
 }
 
-func (app *application) inventoryTransferDetails(w http.ResponseWriter, r *http.Request) {
+func (app *application) bpOpenItems(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
-	itid, err := strconv.Atoi(vars["itid"])
+	bpid, err := strconv.Atoi(vars["bpid"])
 	if err != nil {
 		app.clientError(w, http.StatusBadRequest)
 		return
 	}
 
-	inventoryTransfer, err := app.transactions.InventoryTransferDetails(itid)
-
+	items, err := app.businessPartner.OpenItems(bpid)
 	if err != nil {
-		fmt.Println(err)
-		app.clientError(w, http.StatusBadRequest)
+		app.serverError(w, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(inventoryTransfer)
-
+	_ = json.NewEncoder(w).Encode(items)
 }
 
-func (app *application) invoiceDetails(w http.ResponseWriter, r *http.Request) {
+func (app *application) bpStatement(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
-	iid, err := strconv.Atoi(vars["iid"])
+	bpid, err := strconv.Atoi(vars["bpid"])
 	if err != nil {
 		app.clientError(w, http.StatusBadRequest)
 		return
 	}
 
-	invoice, err := app.transactions.InvoiceDetails(iid)
-
-	if err != nil {
+	startDate := r.URL.Query().Get("start_date")
+	endDate := r.URL.Query().Get("end_date")
+	if startDate == "" || endDate == "" {
 		app.clientError(w, http.StatusBadRequest)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(invoice)
+	statement, err := app.businessPartner.Statement(bpid, startDate, endDate)
+	if errors.Is(err, models.ErrNoRecord) {
+		app.notFound(w)
+		return
+	} else if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	if r.URL.Query().Get("format") == "pdf" {
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"statement-%d-%s.pdf\"", bpid, endDate))
+		err = writeStatementPDF(w, statement)
+		if err != nil {
+			app.serverError(w, err)
+		}
+		return
+	}
 
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(statement)
 }
 
-func (app *application) goodsReceivedNoteDetails(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	grnid, err := strconv.Atoi(vars["grnid"])
+func (app *application) accountChart(w http.ResponseWriter, _ *http.Request) {
+	accounts, err := app.account.ChartOfAccounts()
 	if err != nil {
-		app.clientError(w, http.StatusBadRequest)
+		app.serverError(w, err)
 		return
 	}
 
-	goodsReceivedNote, err := app.goodsReceivedNote.GoodsReceivedNoteDetails(grnid)
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(accounts)
+}
 
+func (app *application) paymentVouchers(w http.ResponseWriter, _ *http.Request) {
+	items, err := app.account.PaymentVouchers()
 	if err != nil {
 		app.clientError(w, http.StatusBadRequest)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(goodsReceivedNote)
+	_ = json.NewEncoder(w).Encode(items)
 
 }
 
-func (app *application) purchaseOrderData(w http.ResponseWriter, r *http.Request) {
+func (app *application) paymentVoucherDetails(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	pid, err := strconv.Atoi(vars["pid"])
 	if err != nil {
@@ -1039,26 +1314,871 @@ func (app *application) purchaseOrderData(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	purchaseOrder, err := app.purchaseOrder.PurchaseOrderData(pid)
-
+	items, err := app.account.PaymentVoucherDetails(pid)
 	if err != nil {
 		app.clientError(w, http.StatusBadRequest)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(purchaseOrder)
+	_ = json.NewEncoder(w).Encode(items)
 
 }
 
-func (app *application) createLandedCost(w http.ResponseWriter, r *http.Request) {
-	err := r.ParseForm()
+func (app *application) accountTransaction(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tid, err := strconv.Atoi(vars["tid"])
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	ledger, err := app.account.Transaction(tid)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(ledger)
+}
+
+func (app *application) accountTrialBalance(w http.ResponseWriter, r *http.Request) {
+	postingdate := r.URL.Query().Get("postingdate")
+
+	accounts, err := app.account.TrialBalance(postingdate)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(accounts)
+}
+
+func (app *application) journalEntryAudit(w http.ResponseWriter, r *http.Request) {
+	entrydate := r.URL.Query().Get("entrydate")
+	postingdate := r.URL.Query().Get("postingdate")
+
+	results, err := app.account.JournalEntriesForAudit(entrydate, postingdate)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(results)
+}
+
+func (app *application) pnlSummary(w http.ResponseWriter, r *http.Request) {
+	startDate := r.URL.Query().Get("startdate")
+	endDate := r.URL.Query().Get("enddate")
+
+	results, err := app.account.AccountsForPNL(startDate, endDate)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(results)
+}
+
+func (app *application) balanceSheetSummary(w http.ResponseWriter, r *http.Request) {
+	postingdate := r.URL.Query().Get("postingdate")
+
+	results, err := app.account.BalanceSheetSummary(postingdate)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(results)
+}
+
+func (app *application) accountBalancesForReporting(w http.ResponseWriter, r *http.Request) {
+	postingdate := r.URL.Query().Get("postingdate")
+
+	results, err := app.account.AccountBalancesForReporting(postingdate)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(results)
+}
+
+func (app *application) invoiceSearch(w http.ResponseWriter, r *http.Request) {
+	startDate := r.URL.Query().Get("startdate")
+	_, err := time.Parse("2006-01-02", startDate)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	endDate := r.URL.Query().Get("enddate")
+	_, err = time.Parse("2006-01-02", endDate)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	officer := r.URL.Query().Get("officer")
+
+	results, err := app.reporting.InvoiceSearch(startDate, endDate, officer)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(results)
+}
+
+func (app *application) inventoryValuation(w http.ResponseWriter, r *http.Request) {
+	date := r.URL.Query().Get("date")
+	_, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	results, err := app.reporting.InventoryValuation(date)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(results)
+}
+
+func (app *application) reorderSuggestions(w http.ResponseWriter, r *http.Request) {
+	results, err := app.reporting.ReorderSuggestions(r.URL.Query().Get("warehouse"))
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(results)
+}
+
+func (app *application) reorderPurchaseOrder(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	if !app.setAuthenticatedUser(w, r) {
+		return
+	}
+
+	requiredParams := []string{"user_id", "warehouse_id", "supplier_id"}
+	for _, param := range requiredParams {
+		if v := r.PostForm.Get(param); v == "" {
+			app.clientError(w, http.StatusBadRequest)
+			return
+		}
+	}
+
+	suggestions, err := app.reporting.ReorderSuggestions(r.PostForm.Get("warehouse_id"))
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	id, err := app.purchaseOrder.CreateFromReorderSuggestions(r.PostForm.Get("user_id"), r.PostForm.Get("supplier_id"), r.PostForm.Get("warehouse_id"), suggestions)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	fmt.Fprintf(w, "%d", id)
+}
+
+func (app *application) createInvoice(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	if !app.setAuthenticatedUser(w, r) {
+		return
+	}
+
+	requiredParams := []string{"user_id", "from_warehouse", "customer_contact", "discount", "items", "request_id"}
+	optionalParams := []string{}
+
+	for _, param := range requiredParams {
+		if v := r.PostForm.Get(param); v == "" {
+			app.clientError(w, http.StatusBadRequest)
+			return
+		}
+	}
+
+	id, err := app.transactions.CreateInvoice(requiredParams, optionalParams, app.fgAPIKey, r.PostForm)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	fmt.Fprintf(w, "%d", id)
+}
+
+func (app *application) createInvoiceReturn(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	iid, err := strconv.Atoi(vars["iid"])
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	err = r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	if !app.setAuthenticatedUser(w, r) {
+		return
+	}
+
+	requiredParams := []string{"user_id", "items"}
+	optionalParams := []string{"remark", "request_id"}
+	for _, param := range requiredParams {
+		if v := r.PostForm.Get(param); v == "" {
+			app.clientError(w, http.StatusBadRequest)
+			return
+		}
+	}
+
+	id, err := app.transactions.CreateInvoiceReturn(iid, requiredParams, optionalParams, r.PostForm)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	fmt.Fprintf(w, "%d", id)
+}
+
+func (app *application) voidInvoice(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	iid, err := strconv.Atoi(vars["iid"])
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	err = r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	if !app.setAuthenticatedUser(w, r) {
+		return
+	}
+
+	requiredParams := []string{"user_id", "reason"}
+	for _, param := range requiredParams {
+		if v := r.PostForm.Get(param); v == "" {
+			app.clientError(w, http.StatusBadRequest)
+			return
+		}
+	}
+
+	tid, err := app.transactions.VoidInvoice(iid, r.PostForm)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	fmt.Fprintf(w, "%d", tid)
+}
+
+func (app *application) inventoryTransferAction(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	requiredParams := []string{"inventory_transfer_id", "user_id", "resolution", "resolution_remarks", "request_id"}
+	optionalParams := []string{}
+	for _, param := range requiredParams {
+		if v := r.PostForm.Get(param); v == "" {
+			app.clientError(w, http.StatusBadRequest)
+			return
+		}
+	}
+
+	id, err := app.transactions.InventoryTransferAction(requiredParams, optionalParams, r.PostForm)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	fmt.Fprintf(w, "%d", id)
+}
+
+func (app *application) createInventoryTransfer(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	if !app.setAuthenticatedUser(w, r) {
+		return
+	}
+
+	requiredParams := []string{"user_id", "from_warehouse_id", "to_warehouse_id", "entries"}
+	optionalParams := []string{"remark"}
+	for _, param := range requiredParams {
+		if v := r.PostForm.Get(param); v == "" {
+			app.clientError(w, http.StatusBadRequest)
+			return
+		}
+	}
+
+	id, err := app.transactions.CreateInventoryTransfer(requiredParams, optionalParams, r.PostForm)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	fmt.Fprintf(w, "%d", id)
+}
+
+func (app *application) createOrder(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	if !app.setAuthenticatedUser(w, r) {
+		return
+	}
+
+	requiredParams := []string{"supplier_id", "warehouse_id", "entries"}
+	optionalParams := []string{"remark"}
+	for _, param := range requiredParams {
+		if v := r.PostForm.Get(param); v == "" {
+			app.clientError(w, http.StatusBadRequest)
+			return
+		}
+	}
+
+	id, err := app.purchaseOrder.CreatePurchaseOrder(requiredParams, optionalParams, r.PostForm)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	fmt.Fprintf(w, "%d", id)
+}
+
+func (app *application) cancelOrderItem(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	requiredParams := []string{"user_id", "purchase_order_id", "item_id", "reason"}
+	for _, param := range requiredParams {
+		if v := r.PostForm.Get(param); v == "" {
+			app.clientError(w, http.StatusBadRequest)
+			return
+		}
+	}
+
+	id, err := app.purchaseOrder.CancelItem(r.PostForm)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	fmt.Fprintf(w, "%d", id)
+}
+
+func (app *application) approveOrder(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	if !app.setAuthenticatedUser(w, r) {
+		return
+	}
+
+	requiredParams := []string{"user_id", "purchase_order_id", "status"}
+	for _, param := range requiredParams {
+		if v := r.PostForm.Get(param); v == "" {
+			app.clientError(w, http.StatusBadRequest)
+			return
+		}
+	}
+
+	id, err := app.purchaseOrder.Approve(r.PostForm)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	fmt.Fprintf(w, "%d", id)
+}
+
+func (app *application) closeOrder(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	requiredParams := []string{"user_id", "purchase_order_id", "reason"}
+	for _, param := range requiredParams {
+		if v := r.PostForm.Get(param); v == "" {
+			app.clientError(w, http.StatusBadRequest)
+			return
+		}
+	}
+
+	id, err := app.purchaseOrder.ClosePurchaseOrder(r.PostForm)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	fmt.Fprintf(w, "%d", id)
+}
+
+func (app *application) getPendingInventoryTransfers(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	userType := vars["type"]
+	wid, err := strconv.Atoi(vars["warehouse"])
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	pendingTransfers, err := app.transactions.GetPendingTransfers(wid, userType)
+
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(pendingTransfers)
+}
+
+func (app *application) salesCommission(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	uid, err := strconv.Atoi(vars["uid"])
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	inventoryTransferItems, err := app.transactions.GetSalesCommission(uid)
+
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(inventoryTransferItems)
+}
+
+func (app *application) cashInHand(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	uid, err := strconv.Atoi(vars["uid"])
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	inventoryTransferItems, err := app.transactions.GetCashInHand(uid)
+
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(inventoryTransferItems)
+}
+
+func (app *application) inventoryTransferItems(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	itid, err := strconv.Atoi(vars["itid"])
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	inventoryTransferItems, err := app.transactions.GetInventoryTransferItems(itid)
+
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(inventoryTransferItems)
+}
+
+func (app *application) getWarehouseStock(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	wid, err := strconv.Atoi(vars["wid"])
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	goodsReceivedNote, err := app.transactions.GetWarehouseStock(wid)
+
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(goodsReceivedNote)
+}
+
+func (app *application) goodsReceivedNoteVariances(w http.ResponseWriter, _ *http.Request) {
+	variances, err := app.goodsReceivedNote.OpenVariances()
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(variances)
+}
+
+func (app *application) resolveGoodsReceivedNoteVariance(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	requiredParams := []string{"user_id", "grn_item_id", "resolution"}
+	for _, param := range requiredParams {
+		if v := r.PostForm.Get(param); v == "" {
+			app.clientError(w, http.StatusBadRequest)
+			return
+		}
+	}
+
+	id, err := app.goodsReceivedNote.ResolveVariance(r.PostForm)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	fmt.Fprintf(w, "%d", id)
+}
+
+func (app *application) purchaseOrderList(w http.ResponseWriter, _ *http.Request) {
+	orders, err := app.purchaseOrder.PurchaseOrderList()
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(orders)
+}
+
+func (app *application) purchaseOrderDetails(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	pid, err := strconv.Atoi(vars["pid"])
+
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	purchaseOrder, err := app.purchaseOrder.PurchaseOrderDetails(pid)
+
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(purchaseOrder)
+
+}
+
+func (app *application) createGoodsReceivedNote(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	requiredParams := []string{"supplier_id", "warehouse_id", "effective_date", "entries"}
+	optionalParams := []string{"remark"}
+	for _, param := range requiredParams {
+		if v := r.PostForm.Get(param); v == "" {
+			app.clientError(w, http.StatusBadRequest)
+			return
+		}
+	}
+
+	id, err := app.goodsReceivedNote.CreateGoodsReceivedNote(requiredParams, optionalParams, r.PostForm)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	fmt.Fprintf(w, "%d", id)
+}
+
+func (app *application) inventoryTransferList(w http.ResponseWriter, r *http.Request) {
+	notes, err := app.transactions.InventoryTransferList()
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(notes)
+}
+
+func (app *application) goodsReceivedNoteList(w http.ResponseWriter, r *http.Request) {
+	notes, err := app.goodsReceivedNote.GoodsReceivedNotesList()
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(notes)
+}
+
+func (app *application) inventoryTransferDetails(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	itid, err := strconv.Atoi(vars["itid"])
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	inventoryTransfer, err := app.transactions.InventoryTransferDetails(itid)
+
+	if err != nil {
+		fmt.Println(err)
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(inventoryTransfer)
+
+}
+
+func (app *application) invoiceDetails(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	iid, err := strconv.Atoi(vars["iid"])
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	invoice, err := app.transactions.InvoiceDetails(iid)
+
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(invoice)
+
+}
+
+func (app *application) goodsReceivedNoteDetails(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	grnid, err := strconv.Atoi(vars["grnid"])
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	goodsReceivedNote, err := app.goodsReceivedNote.GoodsReceivedNoteDetails(grnid)
+
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(goodsReceivedNote)
+
+}
+
+func (app *application) purchaseOrderData(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	pid, err := strconv.Atoi(vars["pid"])
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	purchaseOrder, err := app.purchaseOrder.PurchaseOrderData(pid)
+
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(purchaseOrder)
+
+}
+
+func (app *application) createLandedCost(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	if !app.setAuthenticatedUser(w, r) {
+		return
+	}
+
+	requiredParams := []string{"entries", "user_id"}
+	for _, param := range requiredParams {
+		if v := r.PostForm.Get(param); v == "" {
+			app.clientError(w, http.StatusBadRequest)
+			return
+		}
+	}
+
+	if r.PostForm.Get("grn_id") == "" && r.PostForm.Get("grn_ids") == "" {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	id, err := app.landedCost.CreateLandedCost(requiredParams, r.PostForm)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	fmt.Fprintf(w, "%d", id)
+}
+
+func (app *application) adjustLandedCost(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	if !app.setAuthenticatedUser(w, r) {
+		return
+	}
+
+	requiredParams := []string{"grn_id", "entries", "user_id"}
+	for _, param := range requiredParams {
+		if v := r.PostForm.Get(param); v == "" {
+			app.clientError(w, http.StatusBadRequest)
+			return
+		}
+	}
+
+	id, err := app.landedCost.AdjustLandedCost(r.PostForm)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	fmt.Fprintf(w, "%d", id)
+}
+
+func (app *application) setLandedCostAllocationBasis(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	requiredParams := []string{"landed_cost_type_id", "allocation_basis"}
+	for _, param := range requiredParams {
+		if v := r.PostForm.Get(param); v == "" {
+			app.clientError(w, http.StatusBadRequest)
+			return
+		}
+	}
+
+	id, err := app.landedCost.SetAllocationBasis(r.PostForm)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	fmt.Fprintf(w, "%d", id)
+}
+
+func (app *application) previewLandedCost(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	requiredParams := []string{"entries"}
+	for _, param := range requiredParams {
+		if v := r.PostForm.Get(param); v == "" {
+			app.clientError(w, http.StatusBadRequest)
+			return
+		}
+	}
+
+	if r.PostForm.Get("grn_id") == "" && r.PostForm.Get("grn_ids") == "" {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	allocations, err := app.landedCost.PreviewLandedCost(r.PostForm)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(allocations)
+}
+
+func (app *application) createSupplierReturn(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
 	if err != nil {
 		app.clientError(w, http.StatusBadRequest)
 		return
 	}
 
-	requiredParams := []string{"grn_id", "entries", "user_id"}
+	if !app.setAuthenticatedUser(w, r) {
+		return
+	}
+
+	requiredParams := []string{"user_id", "grn_id", "entries"}
+	optionalParams := []string{"remark"}
 	for _, param := range requiredParams {
 		if v := r.PostForm.Get(param); v == "" {
 			app.clientError(w, http.StatusBadRequest)
@@ -1066,7 +2186,255 @@ func (app *application) createLandedCost(w http.ResponseWriter, r *http.Request)
 		}
 	}
 
-	id, err := app.landedCost.CreateLandedCost(requiredParams, r.PostForm)
+	id, err := app.supplierReturn.CreateSupplierReturn(requiredParams, optionalParams, r.PostForm)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	fmt.Fprintf(w, "%d", id)
+}
+
+func (app *application) createSupplierInvoice(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	if !app.setAuthenticatedUser(w, r) {
+		return
+	}
+
+	requiredParams := []string{"user_id", "supplier_id", "invoice_number", "invoice_date", "entries"}
+	optionalParams := []string{"due_date", "remark"}
+	for _, param := range requiredParams {
+		if v := r.PostForm.Get(param); v == "" {
+			app.clientError(w, http.StatusBadRequest)
+			return
+		}
+	}
+
+	id, err := app.supplierInvoice.CreateSupplierInvoice(requiredParams, optionalParams, r.PostForm)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	fmt.Fprintf(w, "%d", id)
+}
+
+func (app *application) postSupplierInvoice(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	if !app.setAuthenticatedUser(w, r) {
+		return
+	}
+
+	requiredParams := []string{"user_id", "supplier_invoice_id", "remark"}
+	for _, param := range requiredParams {
+		if v := r.PostForm.Get(param); v == "" {
+			app.clientError(w, http.StatusBadRequest)
+			return
+		}
+	}
+
+	id, err := app.supplierInvoice.PostSupplierInvoice(r.PostForm)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	fmt.Fprintf(w, "%d", id)
+}
+
+func (app *application) rejectSupplierInvoice(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	if !app.setAuthenticatedUser(w, r) {
+		return
+	}
+
+	requiredParams := []string{"user_id", "supplier_invoice_id"}
+	optionalParams := []string{"remark"}
+	for _, param := range requiredParams {
+		if v := r.PostForm.Get(param); v == "" {
+			app.clientError(w, http.StatusBadRequest)
+			return
+		}
+	}
+
+	id, err := app.supplierInvoice.Reject(requiredParams, optionalParams, r.PostForm)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	fmt.Fprintf(w, "%d", id)
+}
+
+func (app *application) supplierInvoiceList(w http.ResponseWriter, _ *http.Request) {
+	invoices, err := app.supplierInvoice.List()
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(invoices)
+}
+
+func (app *application) supplierInvoiceDetails(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	siid, err := strconv.Atoi(vars["siid"])
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	invoice, err := app.supplierInvoice.Details(siid)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(invoice)
+}
+
+func (app *application) createStockAdjustment(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	if !app.setAuthenticatedUser(w, r) {
+		return
+	}
+
+	requiredParams := []string{"user_id", "warehouse_id", "entries"}
+	optionalParams := []string{"remark"}
+	for _, param := range requiredParams {
+		if v := r.PostForm.Get(param); v == "" {
+			app.clientError(w, http.StatusBadRequest)
+			return
+		}
+	}
+
+	id, err := app.stockAdjustment.CreateStockAdjustment(requiredParams, optionalParams, r.PostForm)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	fmt.Fprintf(w, "%d", id)
+}
+
+func (app *application) createStockTake(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	requiredParams := []string{"user_id", "warehouse_id"}
+	optionalParams := []string{"remark"}
+	for _, param := range requiredParams {
+		if v := r.PostForm.Get(param); v == "" {
+			app.clientError(w, http.StatusBadRequest)
+			return
+		}
+	}
+
+	id, err := app.stockTake.CreateStockTake(requiredParams, optionalParams, r.PostForm)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	fmt.Fprintf(w, "%d", id)
+}
+
+func (app *application) stockTakeCount(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	requiredParams := []string{"user_id", "stock_take_id", "entries"}
+	optionalParams := []string{}
+	for _, param := range requiredParams {
+		if v := r.PostForm.Get(param); v == "" {
+			app.clientError(w, http.StatusBadRequest)
+			return
+		}
+	}
+
+	session, err := app.stockTake.SubmitCount(requiredParams, optionalParams, r.PostForm)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	fmt.Fprintf(w, "%d", session)
+}
+
+func (app *application) stockTakeApprove(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	if !app.setAuthenticatedUser(w, r) {
+		return
+	}
+
+	requiredParams := []string{"user_id", "stock_take_id", "reason_id"}
+	optionalParams := []string{}
+	for _, param := range requiredParams {
+		if v := r.PostForm.Get(param); v == "" {
+			app.clientError(w, http.StatusBadRequest)
+			return
+		}
+	}
+
+	id, err := app.stockTake.Approve(requiredParams, optionalParams, r.PostForm)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	fmt.Fprintf(w, "%d", id)
+}
+
+func (app *application) stockTakeCancel(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	requiredParams := []string{"user_id", "stock_take_id"}
+	optionalParams := []string{"remark"}
+	for _, param := range requiredParams {
+		if v := r.PostForm.Get(param); v == "" {
+			app.clientError(w, http.StatusBadRequest)
+			return
+		}
+	}
+
+	id, err := app.stockTake.Cancel(requiredParams, optionalParams, r.PostForm)
 	if err != nil {
 		app.serverError(w, err)
 		return
@@ -1074,3 +2442,32 @@ func (app *application) createLandedCost(w http.ResponseWriter, r *http.Request)
 
 	fmt.Fprintf(w, "%d", id)
 }
+
+func (app *application) stockTakeList(w http.ResponseWriter, _ *http.Request) {
+	results, err := app.stockTake.List()
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(results)
+}
+
+func (app *application) stockTakeVariance(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	stid, err := strconv.Atoi(vars["stid"])
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	results, err := app.stockTake.Variance(stid)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(results)
+}
@@ -2,11 +2,14 @@ package main
 
 import (
 	"bytes"
+	"encoding/csv"
+	"errors"
 	"fmt"
 	"mime/multipart"
 	"net/http"
 	"path/filepath"
 	"runtime/debug"
+	"strconv"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
@@ -14,6 +17,7 @@ import (
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/dgrijalva/jwt-go"
 	"github.com/globalsign/mgo/bson"
+	"github.com/ssrdive/basara/pkg/models"
 )
 
 func (app *application) serverError(w http.ResponseWriter, err error) {
@@ -31,11 +35,40 @@ func (app *application) notFound(w http.ResponseWriter) {
 	app.clientError(w, http.StatusNotFound)
 }
 
+// writeCSV writes records as a CSV attachment with the given file name
+func (app *application) writeCSV(w http.ResponseWriter, filename string, records [][]string) {
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	cw := csv.NewWriter(w)
+	_ = cw.WriteAll(records)
+}
+
 func (app *application) extractUser(r *http.Request) jwt.Claims {
 	ctx := r.Context()
 	return ctx.Value(contextKey("User")).(jwt.MapClaims)
 }
 
+// setAuthenticatedUser replaces the user_id of a parsed form with the user the
+// request token was issued to so that permission checks cannot be made as another user.
+// It writes an unauthorized or server error response and returns false on failure
+func (app *application) setAuthenticatedUser(w http.ResponseWriter, r *http.Request) bool {
+	claims, _ := app.extractUser(r).(jwt.MapClaims)
+	username, _ := claims["username"].(string)
+
+	id, err := app.user.ID(username)
+	if errors.Is(err, models.ErrNoRecord) {
+		app.clientError(w, http.StatusUnauthorized)
+		return false
+	} else if err != nil {
+		app.serverError(w, err)
+		return false
+	}
+
+	r.PostForm.Set("user_id", strconv.Itoa(id))
+	return true
+}
+
 func (app *application) getS3Session(endpoint, region string) (*session.Session, error) {
 	s, err := session.NewSession(&aws.Config{
 		Endpoint: &endpoint,
@@ -30,9 +30,19 @@ type application struct {
 	item              *mysql.ItemModel
 	businessPartner   *mysql.BusinessPartnerModel
 	account           *scribe.AccountModel
+	accountPosting    *mysql.AccountPostingModel
 	purchaseOrder     *mysql.PurchaseOrderModel
 	goodsReceivedNote *mysql.GoodsReceivedNoteModel
 	landedCost        *mysql.LandedCostModel
+	supplierReturn    *mysql.SupplierReturnModel
+	supplierInvoice   *mysql.SupplierInvoiceModel
+	stockAdjustment   *mysql.StockAdjustmentModel
+	stockTake         *mysql.StockTakeModel
+	exchangeRate      *mysql.ExchangeRateModel
+	cheque            *mysql.ChequeModel
+	bankRec           *mysql.BankReconciliationModel
+	postingRule       *mysql.PostingRuleModel
+	financialPeriod   *mysql.FinancialPeriodModel
 	transactions      *mysql.Transactions
 	reporting         *mysql.ReportingModel
 }
@@ -48,6 +58,8 @@ func main() {
 	s3bucket := flag.String("bucket", "agrivest", "AWS S3 bucket")
 	fgAPIKey := flag.String("fgAPIKey", "", "FarmGear Text Message API Key")
 	runtimeEnv := flag.String("renv", "prod", "Runtime environment mode")
+	bankMatchDays := flag.Int("bankMatchDays", 7, "Days either side of a bank statement line searched for matching account entries")
+	poApprovalThreshold := flag.Float64("poApprovalThreshold", 0, "Purchase order value up to which any user can approve")
 	logPath := flag.String("logpath", "/var/www/farmgear.app/logs/", "Path to create or alter log files")
 	flag.Parse()
 
@@ -85,13 +97,33 @@ func main() {
 		item:              &mysql.ItemModel{DB: db},
 		businessPartner:   &mysql.BusinessPartnerModel{DB: db},
 		account:           &scribe.AccountModel{DB: db},
-		purchaseOrder:     &mysql.PurchaseOrderModel{DB: db},
+		accountPosting:    &mysql.AccountPostingModel{DB: db, Account: &scribe.AccountModel{DB: db}},
+		purchaseOrder:     &mysql.PurchaseOrderModel{DB: db, ApprovalThreshold: *poApprovalThreshold},
 		goodsReceivedNote: &mysql.GoodsReceivedNoteModel{DB: db},
 		landedCost:        &mysql.LandedCostModel{DB: db},
+		supplierReturn:    &mysql.SupplierReturnModel{DB: db},
+		supplierInvoice:   &mysql.SupplierInvoiceModel{DB: db},
+		stockAdjustment:   &mysql.StockAdjustmentModel{DB: db},
+		stockTake:         &mysql.StockTakeModel{DB: db},
+		exchangeRate:      &mysql.ExchangeRateModel{DB: db},
+		cheque:            &mysql.ChequeModel{DB: db},
+		bankRec:           &mysql.BankReconciliationModel{DB: db, MatchDateRange: *bankMatchDays},
+		postingRule:       &mysql.PostingRuleModel{DB: db},
+		financialPeriod:   &mysql.FinancialPeriodModel{DB: db},
 		transactions:      &mysql.Transactions{DB: db, TransactionsLogger: transactionsLog},
 		reporting:         &mysql.ReportingModel{DB: db},
 	}
 
+	err = app.postingRule.Validate()
+	if err != nil {
+		errorLog.Fatal(err)
+	}
+
+	err = app.financialPeriod.Validate()
+	if err != nil {
+		errorLog.Print(err)
+	}
+
 	srv := &http.Server{
 		Addr:     *addr,
 		ErrorLog: errorLog,
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/jung-kurt/gofpdf"
+	"github.com/ssrdive/basara/pkg/models"
+)
+
+// statementColumns holds the headings and widths in millimetres of the
+// business partner statement table
+var statementColumns = []struct {
+	Heading string
+	Width   float64
+}{
+	{"Date", 22},
+	{"Ref", 16},
+	{"Description", 76},
+	{"Debit", 22},
+	{"Credit", 22},
+	{"Balance", 24},
+}
+
+// writeStatementPDF renders a business partner statement of account as an A4 PDF
+func writeStatementPDF(w io.Writer, statement models.BusinessPartnerStatement) error {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-15)
+		pdf.SetFont("Arial", "I", 8)
+		pdf.CellFormat(0, 10, fmt.Sprintf("Page %d", pdf.PageNo()), "", 0, "C", false, 0, "")
+	})
+	pdf.AddPage()
+
+	pdf.SetFont("Arial", "B", 14)
+	pdf.CellFormat(0, 8, "Statement of Account", "", 1, "L", false, 0, "")
+	pdf.SetFont("Arial", "", 10)
+	pdf.CellFormat(0, 6, tr(statement.BusinessPartner), "", 1, "L", false, 0, "")
+	pdf.CellFormat(0, 6, fmt.Sprintf("%s to %s", statement.StartDate, statement.EndDate), "", 1, "L", false, 0, "")
+	pdf.Ln(4)
+
+	pdf.SetFont("Arial", "B", 9)
+	for _, c := range statementColumns {
+		pdf.CellFormat(c.Width, 7, c.Heading, "1", 0, "C", false, 0, "")
+	}
+	pdf.Ln(-1)
+
+	pdf.SetFont("Arial", "", 9)
+	statementRow(pdf, []string{statement.StartDate, "", "Balance brought forward", "", "", statementAmount(statement.OpeningBalance)})
+	for _, e := range statement.Entries {
+		debit, credit := "", ""
+		if e.Type == "DR" {
+			debit = statementAmount(e.Amount)
+		} else {
+			credit = statementAmount(e.Amount)
+		}
+		statementRow(pdf, []string{e.EffectiveDate, strconv.Itoa(e.TransactionID), tr(e.Remark), debit, credit, statementAmount(e.Balance)})
+	}
+
+	pdf.SetFont("Arial", "B", 9)
+	statementRow(pdf, []string{statement.EndDate, "", "Closing balance", "", "", statementAmount(statement.ClosingBalance)})
+
+	return pdf.Output(w)
+}
+
+// statementRow writes a row of the statement table truncating the description
+// to the width of its column
+func statementRow(pdf *gofpdf.Fpdf, values []string) {
+	for i, c := range statementColumns {
+		value := values[i]
+		for value != "" && pdf.GetStringWidth(value) > c.Width-2 {
+			value = value[:len(value)-1]
+		}
+
+		align := "L"
+		if i >= 3 {
+			align = "R"
+		}
+		pdf.CellFormat(c.Width, 6, value, "1", 0, align, false, 0, "")
+	}
+	pdf.Ln(-1)
+}
+
+// statementAmount formats an amount for the statement. Credit balances are
+// shown in brackets
+func statementAmount(amount float64) string {
+	if amount < 0 {
+		return fmt.Sprintf("(%.2f)", -amount)
+	}
+	return fmt.Sprintf("%.2f", amount)
+}
@@ -95,10 +95,12 @@ type TransferItem struct {
 }
 
 type PurchaseOrderEntry struct {
-	OrderID    int     `json:"order_id"`
-	Supplier   string  `json:"supplier"`
-	Warehouse  string  `json:"warehouse"`
-	TotalPrice float64 `json:"total_price"`
+	OrderID        int     `json:"order_id"`
+	Supplier       string  `json:"supplier"`
+	Warehouse      string  `json:"warehouse"`
+	TotalPrice     float64 `json:"total_price"`
+	Status         string  `json:"status"`
+	ApprovalStatus string  `json:"approval_status"`
 }
 
 type PurchaseOrderSummary struct {
@@ -111,6 +113,15 @@ type PurchaseOrderSummary struct {
 	DiscountAmount      sql.NullString     `json:"discount_amount"`
 	TotalPrice          sql.NullString     `json:"total_price"`
 	Remarks             sql.NullString     `json:"remarks"`
+	CurrencyCode        sql.NullString     `json:"currency_code"`
+	ExchangeRate        sql.NullString     `json:"exchange_rate"`
+	Status              sql.NullString     `json:"status"`
+	ClosedBy            sql.NullString     `json:"closed_by"`
+	ClosedOn            sql.NullString     `json:"closed_on"`
+	CloseReason         sql.NullString     `json:"close_reason"`
+	ApprovalStatus      sql.NullString     `json:"approval_status"`
+	ApprovedBy          sql.NullString     `json:"approved_by"`
+	ApprovedOn          sql.NullString     `json:"approved_on"`
 	OrderItemDetails    []OrderItemDetails `json:"order_item_details"`
 }
 
@@ -121,6 +132,8 @@ type OrderItemDetails struct {
 	UnitPrice  sql.NullString `json:"unit_price"`
 	Quantity   sql.NullString `json:"quantity"`
 	TotalPrice sql.NullString `json:"total_price"`
+	Received   sql.NullString `json:"received"`
+	Cancelled  sql.NullString `json:"cancelled"`
 }
 
 type GRNItemEntry struct {
@@ -174,6 +187,28 @@ type InvoiceItemDetails struct {
 	Price     float64 `json:"price"`
 }
 
+type InvoiceItemForReturn struct {
+	InvoiceItemID               int
+	EntrySpecifier              string
+	WarehouseID                 int
+	ItemID                      int
+	GoodsReceivedNoteID         int
+	InventoryTransferID         sql.NullInt32
+	Qty                         int
+	ReturnedQty                 int
+	CostPriceWithoutLandedCosts float64
+	CostPrice                   float64
+	Price                       float64
+	Discount                    float64
+	VoidedOn                    sql.NullString
+}
+
+type AccountTransactionEntry struct {
+	AccountID int
+	Type      string
+	Amount    float64
+}
+
 type InventoryTransferSummary struct {
 	InventoryTransferID int                            `json:"inventory_transfer_id"`
 	Created             string                         `json:"created"`
@@ -204,16 +239,21 @@ type GoodReceivedNoteSummary struct {
 	DiscountAmount      sql.NullString   `json:"discount_amount"`
 	TotalPrice          sql.NullString   `json:"total_price"`
 	Remarks             sql.NullString   `json:"remarks"`
+	CurrencyCode        sql.NullString   `json:"currency_code"`
+	ExchangeRate        sql.NullString   `json:"exchange_rate"`
 	GRNItemDetails      []GRNItemDetails `json:"grn_item_details"`
 }
 
 type GRNItemDetails struct {
-	GRNID      sql.NullString `json:"grn_id"`
-	ItemID     sql.NullString `json:"item_id"`
-	ItemName   sql.NullString `json:"item_name"`
-	UnitPrice  sql.NullString `json:"unit_price"`
-	Quantity   sql.NullString `json:"quantity"`
-	TotalPrice sql.NullString `json:"total_price"`
+	GRNID          sql.NullString `json:"grn_id"`
+	ItemID         sql.NullString `json:"item_id"`
+	ItemName       sql.NullString `json:"item_name"`
+	UnitPrice      sql.NullString `json:"unit_price"`
+	Quantity       sql.NullString `json:"quantity"`
+	TotalPrice     sql.NullString `json:"total_price"`
+	QtyVariance    sql.NullString `json:"qty_variance"`
+	PriceVariance  sql.NullString `json:"price_variance"`
+	VarianceStatus sql.NullString `json:"variance_status"`
 }
 
 type PurchaseOrderData struct {
@@ -222,6 +262,8 @@ type PurchaseOrderData struct {
 	WarehouseID         sql.NullString  `json:"warehouse_id"`
 	DiscountType        sql.NullString  `json:"discount_type"`
 	DiscountAmount      sql.NullString  `json:"discount_amount"`
+	CurrencyCode        sql.NullString  `json:"currency_code"`
+	ExchangeRate        sql.NullString  `json:"exchange_rate"`
 	PriceBeforeDiscount float64         `json:"price_before_discount"`
 	TotalPrice          float64         `json:"total_price"`
 	OrderItemData       []OrderItemData `json:"order_item_details"`
@@ -235,17 +277,22 @@ type OrderItemData struct {
 }
 
 type LandedCostItemEntry struct {
-	CostTypeID string `json:"landed_cost_type_id"`
-	Amount     string `json:"amount"`
+	CostTypeID   string `json:"landed_cost_type_id"`
+	Amount       string `json:"amount"`
+	CurrencyCode string `json:"currency_code"`
+	ExchangeRate string `json:"exchange_rate"`
 }
 
 type GRNItemDetailsWithTotal struct {
-	GRNID          sql.NullString `json:"grn_id"`
-	ItemID         int            `json:"item_id"`
-	ToatlCostPrice float64        `json:"total_cost_price"`
-	Quantity       float64        `json:"quantity"`
-	TotalPrice     float64        `json:"total_price"`
-	WarehouseId    int            `json:"warehouse_id"`
+	GRNID               sql.NullString `json:"grn_id"`
+	ItemID              int            `json:"item_id"`
+	ToatlCostPrice      float64        `json:"total_cost_price"`
+	Quantity            float64        `json:"quantity"`
+	TotalPrice          float64        `json:"total_price"`
+	WarehouseId         int            `json:"warehouse_id"`
+	Weight              float64        `json:"weight"`
+	Volume              float64        `json:"volume"`
+	GoodsReceivedNoteID int            `json:"goods_received_note_id"`
 }
 
 type WarehouseStockItem struct {
@@ -284,6 +331,41 @@ type WarehouseItemStockWithDocumentIDsAndPrices struct {
 	Price                       float64
 }
 
+type GRNStockEntry struct {
+	EntrySpecifier string
+	Qty            int
+	CostPrice      float64
+}
+
+type StockAdjustmentEntry struct {
+	ItemID   string `json:"item_id"`
+	Quantity string `json:"qty"`
+	ReasonID string `json:"reason_id"`
+}
+
+type StockTakeEntry struct {
+	ID                int            `json:"id"`
+	Created           string         `json:"created"`
+	Warehouse         string         `json:"warehouse"`
+	CreatedBy         string         `json:"created_by"`
+	Status            string         `json:"status"`
+	ApprovedBy        sql.NullString `json:"approved_by"`
+	ApprovedOn        sql.NullString `json:"approved_on"`
+	StockAdjustmentID sql.NullInt32  `json:"stock_adjustment_id"`
+}
+
+type StockTakeVariance struct {
+	ID            int     `json:"id"`
+	ItemID        string  `json:"item_id"`
+	ItemName      string  `json:"item_name"`
+	SnapshotQty   int     `json:"snapshot_qty"`
+	OnHandQty     int     `json:"on_hand_qty"`
+	CountedQty    int     `json:"counted_qty"`
+	Variance      int     `json:"variance"`
+	UnitCost      float64 `json:"unit_cost"`
+	VarianceValue float64 `json:"variance_value"`
+}
+
 type PendingInventoryTransfer struct {
 	Id      int    `json:"id"`
 	Created string `json:"created"`
@@ -321,8 +403,16 @@ type InvoiceSearchItem struct {
 }
 
 type BPPaymentEntry struct {
-	BP     string
-	Amount string
+	BP            string
+	Amount        string
+	CurrencyCode  string                `json:"currency_code"`
+	ForeignAmount string                `json:"foreign_amount"`
+	Allocations   []BPPaymentAllocation `json:"allocations"`
+}
+
+type BPPaymentAllocation struct {
+	GRNID  string `json:"grn_id"`
+	Amount string `json:"amount"`
 }
 
 type BusinessPartnerBalanceDetail struct {
@@ -340,5 +430,394 @@ type CurrentStock struct {
 	ItemID    string `json:"item_id"`
 	Name      string `json:"item_name"`
 	Qty       int    `json:"qty"`
-	FloatQty  int    `json:"float_qty""`
+	FloatQty  int    `json:"float_qty"`
+}
+
+type ItemMovementEntry struct {
+	WarehouseID  int
+	Warehouse    string
+	Date         string
+	DocumentType string
+	DocumentID   int
+	Quantity     int
+}
+
+type ItemMovement struct {
+	Date         string `json:"date"`
+	DocumentType string `json:"document_type"`
+	DocumentID   int    `json:"document_id"`
+	Quantity     int    `json:"qty"`
+	Balance      int    `json:"balance"`
+}
+
+type ItemStockCard struct {
+	WarehouseID    int            `json:"warehouse_id"`
+	Warehouse      string         `json:"warehouse"`
+	OpeningBalance int            `json:"opening_balance"`
+	ClosingBalance int            `json:"closing_balance"`
+	Movements      []ItemMovement `json:"movements"`
+}
+
+type InventoryValuationItem struct {
+	WarehouseID     int     `json:"warehouse_id"`
+	Warehouse       string  `json:"warehouse"`
+	ID              int     `json:"id"`
+	ItemID          string  `json:"item_id"`
+	ItemName        string  `json:"item_name"`
+	Quantity        int     `json:"qty"`
+	CostValue       float64 `json:"cost_value"`
+	LandedCostValue float64 `json:"landed_cost_value"`
+}
+
+type InventoryValuation struct {
+	Date                 string                   `json:"date"`
+	TotalCostValue       float64                  `json:"total_cost_value"`
+	TotalLandedCostValue float64                  `json:"total_landed_cost_value"`
+	StockAccountBalance  float64                  `json:"stock_account_balance"`
+	Difference           float64                  `json:"difference"`
+	Reconciled           bool                     `json:"reconciled"`
+	Items                []InventoryValuationItem `json:"items"`
+}
+
+type ReorderLevelStatus struct {
+	WarehouseID int     `json:"warehouse_id"`
+	Warehouse   string  `json:"warehouse"`
+	ID          int     `json:"id"`
+	ItemID      string  `json:"item_id"`
+	ItemName    string  `json:"item_name"`
+	MinQty      int     `json:"min_qty"`
+	MaxQty      int     `json:"max_qty"`
+	OnHand      int     `json:"on_hand"`
+	InTransit   int     `json:"in_transit"`
+	OnOrder     int     `json:"on_order"`
+	SupplierID  int     `json:"supplier_id"`
+	Supplier    string  `json:"supplier"`
+	UnitPrice   float64 `json:"unit_price"`
+}
+
+type ReorderSuggestion struct {
+	ReorderLevelStatus
+	SuggestedQty int `json:"suggested_qty"`
+}
+
+type ReorderSupplierSuggestions struct {
+	SupplierID int                 `json:"supplier_id"`
+	Supplier   string              `json:"supplier"`
+	Items      []ReorderSuggestion `json:"items"`
+}
+
+type PurchaseOrderItemRemaining struct {
+	ID        int
+	ItemID    int
+	Remaining float64
+}
+
+type PurchaseOrderItemLine struct {
+	ID        int
+	Remaining float64
+	UnitPrice float64
+}
+
+type SupplierReceiptTolerance struct {
+	QtyTolerance   float64
+	PriceTolerance float64
+	Action         string
+}
+
+type GRNItemVariance struct {
+	ID               int     `json:"id"`
+	GRNID            int     `json:"grn_id"`
+	PurchaseOrderID  int     `json:"purchase_order_id"`
+	Supplier         string  `json:"supplier"`
+	ItemID           string  `json:"item_id"`
+	ItemName         string  `json:"item_name"`
+	Quantity         float64 `json:"qty"`
+	OpenOrderQty     float64 `json:"open_order_qty"`
+	QtyVariance      float64 `json:"qty_variance"`
+	UnitPrice        float64 `json:"unit_price"`
+	OrderedUnitPrice float64 `json:"ordered_unit_price"`
+	PriceVariance    float64 `json:"price_variance"`
+	Created          string  `json:"created"`
+}
+
+type SupplierInvoiceItemEntry struct {
+	GRNID     string `json:"grn_id"`
+	ItemID    string `json:"item_id"`
+	Quantity  string `json:"qty"`
+	UnitPrice string `json:"unit_price"`
+}
+
+type SupplierInvoiceGRNItem struct {
+	SupplierID     int
+	LandedCostID   int
+	CurrencyCode   string
+	ExchangeRate   float64
+	Qty            float64
+	UnitPrice      float64
+	OrderUnitPrice float64
+	InvoicedQty    float64
+}
+
+type SupplierInvoiceEntry struct {
+	ID            int     `json:"id"`
+	Supplier      string  `json:"supplier"`
+	InvoiceNumber string  `json:"invoice_number"`
+	InvoiceDate   string  `json:"invoice_date"`
+	DueDate       string  `json:"due_date"`
+	TotalAmount   float64 `json:"total_amount"`
+	GRNValue      float64 `json:"grn_value"`
+	Status        string  `json:"status"`
+}
+
+type SupplierInvoiceItem struct {
+	ID             int     `json:"id"`
+	GRNID          int     `json:"grn_id"`
+	ItemID         string  `json:"item_id"`
+	ItemName       string  `json:"item_name"`
+	Quantity       float64 `json:"qty"`
+	UnitPrice      float64 `json:"unit_price"`
+	TotalPrice     float64 `json:"total_price"`
+	GRNUnitPrice   float64 `json:"grn_unit_price"`
+	OrderUnitPrice float64 `json:"order_unit_price"`
+	QtyVariance    float64 `json:"qty_variance"`
+	PriceVariance  float64 `json:"price_variance"`
+	MatchStatus    string  `json:"match_status"`
+}
+
+type SupplierInvoiceSummary struct {
+	SupplierInvoiceEntry
+	Items []SupplierInvoiceItem `json:"items"`
+}
+
+type LandedCostAllocationCost struct {
+	CostTypeID       string
+	AllocationBasis  string
+	Amount           float64
+	ForeignAmount    float64
+	CurrencyCode     string
+	ExchangeRate     float64
+	ExpenseAccountID int
+	PayableAccountID int
+}
+
+type GRNItemRemainingStock struct {
+	ItemID int
+	Qty    float64
+}
+
+type LandedCostAllocationLine struct {
+	CostTypeID      string  `json:"landed_cost_type_id"`
+	AllocationBasis string  `json:"allocation_basis"`
+	Amount          float64 `json:"amount"`
+}
+
+type LandedCostAllocation struct {
+	GoodsReceivedNoteID int                        `json:"goods_received_note_id"`
+	GRNItemID           sql.NullString             `json:"grn_item_id"`
+	ItemID              int                        `json:"item_id"`
+	WarehouseID         int                        `json:"warehouse_id"`
+	Quantity            float64                    `json:"quantity"`
+	TotalCostPrice      float64                    `json:"total_cost_price"`
+	UnitCost            float64                    `json:"unit_cost"`
+	LandedCost          float64                    `json:"landed_cost"`
+	UnitLandedCost      float64                    `json:"unit_landed_cost"`
+	Price               float64                    `json:"price"`
+	Breakdown           []LandedCostAllocationLine `json:"breakdown"`
+}
+
+type BusinessPartnerForeignBalance struct {
+	ID              int     `json:"id"`
+	BusinessPartner string  `json:"business_partner"`
+	CurrencyCode    string  `json:"currency_code"`
+	ForeignBalance  float64 `json:"foreign_balance"`
+	BaseBalance     float64 `json:"base_balance"`
+}
+
+type ExchangeRate struct {
+	ID           int     `json:"id"`
+	CurrencyCode string  `json:"currency_code"`
+	RateDate     string  `json:"rate_date"`
+	Rate         float64 `json:"rate"`
+	EnteredBy    string  `json:"entered_by"`
+}
+
+type SupplierOpenItem struct {
+	GRNID          int     `json:"grn_id"`
+	EffectiveDate  string  `json:"effective_date"`
+	CurrencyCode   string  `json:"currency_code"`
+	InvoiceNumbers string  `json:"invoice_numbers"`
+	Invoiced       float64 `json:"invoiced"`
+	Returned       float64 `json:"returned"`
+	Allocated      float64 `json:"allocated"`
+	OpenAmount     float64 `json:"open_amount"`
+}
+
+type SupplierOnAccount struct {
+	CurrencyCode string  `json:"currency_code"`
+	Amount       float64 `json:"amount"`
+}
+
+type SupplierOpenItems struct {
+	BusinessPartnerID int                 `json:"business_partner_id"`
+	OnAccount         []SupplierOnAccount `json:"on_account"`
+	Items             []SupplierOpenItem  `json:"items"`
+}
+
+type BusinessPartnerAgingEntry struct {
+	BusinessPartnerID int     `json:"business_partner_id"`
+	BusinessPartner   string  `json:"business_partner"`
+	GRNID             int     `json:"grn_id"`
+	TransactionID     int     `json:"transaction_id"`
+	EffectiveDate     string  `json:"effective_date"`
+	CurrencyCode      string  `json:"currency_code"`
+	ExchangeRate      float64 `json:"exchange_rate"`
+	Amount            float64 `json:"amount"`
+	Remark            string  `json:"remark"`
+}
+
+type BusinessPartnerAgingItem struct {
+	BusinessPartnerAgingEntry
+	ForeignOpenAmount float64 `json:"foreign_open_amount"`
+	OpenAmount        float64 `json:"open_amount"`
+	Days              int     `json:"days"`
+	Bucket            string  `json:"bucket"`
+}
+
+type BusinessPartnerAging struct {
+	ID         int     `json:"id"`
+	Name       string  `json:"name"`
+	Current    float64 `json:"current"`
+	Days1To30  float64 `json:"days_1_30"`
+	Days31To60 float64 `json:"days_31_60"`
+	Days61To90 float64 `json:"days_61_90"`
+	Over90     float64 `json:"over_90"`
+	Balance    float64 `json:"balance"`
+}
+
+type BusinessPartnerStatementEntry struct {
+	BusinessPartnerBalanceDetail
+	Balance float64 `json:"balance"`
+}
+
+type BusinessPartnerStatement struct {
+	BusinessPartnerID int                             `json:"business_partner_id"`
+	BusinessPartner   string                          `json:"business_partner"`
+	StartDate         string                          `json:"start_date"`
+	EndDate           string                          `json:"end_date"`
+	OpeningBalance    float64                         `json:"opening_balance"`
+	ClosingBalance    float64                         `json:"closing_balance"`
+	Entries           []BusinessPartnerStatementEntry `json:"entries"`
+}
+
+type Cheque struct {
+	ID            int     `json:"id"`
+	ChequeNumber  string  `json:"cheque_number"`
+	Account       string  `json:"account"`
+	Payee         string  `json:"payee"`
+	IssueDate     string  `json:"issue_date"`
+	EffectiveDate string  `json:"effective_date"`
+	Amount        float64 `json:"amount"`
+	Status        string  `json:"status"`
+	TransactionID int     `json:"transaction_id"`
+}
+
+type BusinessPartnerFinancialReversal struct {
+	BusinessPartnerID int
+	Type              string
+	Amount            float64
+	CurrencyCode      string
+	ForeignAmount     float64
+	ExchangeRate      float64
+}
+
+type BankStatementLine struct {
+	ID                   int     `json:"id"`
+	TransactionDate      string  `json:"transaction_date"`
+	Description          string  `json:"description"`
+	Reference            string  `json:"reference"`
+	Amount               float64 `json:"amount"`
+	AccountTransactionID int     `json:"account_transaction_id"`
+	TransactionID        int     `json:"transaction_id"`
+	MatchType            string  `json:"match_type"`
+}
+
+type BankReconciliationEntry struct {
+	AccountTransactionID int     `json:"account_transaction_id"`
+	TransactionID        int     `json:"transaction_id"`
+	PostingDate          string  `json:"posting_date"`
+	ChequeNumber         string  `json:"cheque_number"`
+	Remark               string  `json:"remark"`
+	Amount               float64 `json:"amount"`
+}
+
+type BankReconciliation struct {
+	AccountID               int                       `json:"account_id"`
+	Date                    string                    `json:"date"`
+	BookBalance             float64                   `json:"book_balance"`
+	OutstandingDeposits     []BankReconciliationEntry `json:"outstanding_deposits"`
+	UnpresentedCheques      []BankReconciliationEntry `json:"unpresented_cheques"`
+	UnmatchedStatementLines []BankStatementLine       `json:"unmatched_statement_lines"`
+	AdjustedBankBalance     float64                   `json:"adjusted_bank_balance"`
+	StatementBalance        *float64                  `json:"statement_balance"`
+	Difference              float64                   `json:"difference"`
+}
+
+type PostingRule struct {
+	ID             int    `json:"id"`
+	Rule           string `json:"rule"`
+	ItemCategoryID int    `json:"item_category_id"`
+	ItemCategory   string `json:"item_category"`
+	WarehouseID    int    `json:"warehouse_id"`
+	Warehouse      string `json:"warehouse"`
+	AccountID      int    `json:"account_id"`
+	Account        string `json:"account"`
+}
+
+type FinancialYear struct {
+	ID        int    `json:"id"`
+	Name      string `json:"name"`
+	StartDate string `json:"start_date"`
+	EndDate   string `json:"end_date"`
+	Status    string `json:"status"`
+}
+
+type FinancialPeriod struct {
+	ID              int    `json:"id"`
+	Name            string `json:"name"`
+	StartDate       string `json:"start_date"`
+	EndDate         string `json:"end_date"`
+	Status          string `json:"status"`
+	StatusChangedBy string `json:"status_changed_by"`
+	StatusChangedOn string `json:"status_changed_on"`
+}
+
+type YearEndClosingEntry struct {
+	AccountID   int     `json:"account_id"`
+	MainAccount string  `json:"main_account"`
+	AccountName string  `json:"account_name"`
+	Balance     float64 `json:"balance"`
+	Debit       float64 `json:"debit"`
+	Credit      float64 `json:"credit"`
+}
+
+type YearEndClosing struct {
+	FinancialYearID           int                   `json:"financial_year_id"`
+	Name                      string                `json:"name"`
+	StartDate                 string                `json:"start_date"`
+	EndDate                   string                `json:"end_date"`
+	Status                    string                `json:"status"`
+	RetainedEarningsAccountID int                   `json:"retained_earnings_account_id"`
+	NetProfit                 float64               `json:"net_profit"`
+	Entries                   []YearEndClosingEntry `json:"entries"`
+}
+
+type SupplierInvoiceVarianceLine struct {
+	GoodsReceivedNoteID int
+	ItemID              int
+	ItemCategoryID      string
+	WarehouseID         int
+	InvoiceAmount       float64
+	GRNValue            float64
+	ReceivedQty         float64
+	RemainingQty        float64
 }
@@ -0,0 +1,64 @@
+package mysql
+
+import (
+	"database/sql"
+
+	"github.com/ssrdive/scribe"
+)
+
+// AccountPostingModel struct holds database instance and the scribe account model. It
+// checks the financial period of the postings made through scribe, which does not know
+// the financial calendar
+type AccountPostingModel struct {
+	DB      *sql.DB
+	Account *scribe.AccountModel
+}
+
+// PaymentVoucher pays the entries from an account
+func (m *AccountPostingModel) PaymentVoucher(userID, postingDate, fromAccountID, amount, entries, remark, dueDate, checkNumber, payee string) (int64, error) {
+	return m.post(userID, postingDate, func() (int64, error) {
+		return m.Account.PaymentVoucher(userID, postingDate, fromAccountID, amount, entries, remark, dueDate, checkNumber, payee)
+	})
+}
+
+// Deposit deposits the entries to an account
+func (m *AccountPostingModel) Deposit(userID, postingDate, toAccountID, amount, entries, remark string) (int64, error) {
+	return m.post(userID, postingDate, func() (int64, error) {
+		return m.Account.Deposit(userID, postingDate, toAccountID, amount, entries, remark)
+	})
+}
+
+// JournalEntry issues journal entries
+func (m *AccountPostingModel) JournalEntry(userID, postingDate, remark, entries string) (int64, error) {
+	return m.post(userID, postingDate, func() (int64, error) {
+		return m.Account.JournalEntry(userID, postingDate, remark, entries)
+	})
+}
+
+// post checks the financial period of the posting date before making the posting. The
+// period stays locked until the posting is made so that it cannot be closed in between
+func (m *AccountPostingModel) post(userID, postingDate string, posting func() (int64, error)) (int64, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+			return
+		}
+		_ = tx.Commit()
+	}()
+
+	err = checkPostingPeriod(tx, userID, postingDate)
+	if err != nil {
+		return 0, err
+	}
+
+	tid, err := posting()
+	if err != nil {
+		return 0, err
+	}
+
+	return tid, nil
+}
@@ -0,0 +1,365 @@
+package mysql
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ssrdive/basara/pkg/models"
+	"github.com/ssrdive/basara/pkg/sql/queries"
+	"github.com/ssrdive/mysequel"
+)
+
+// BankReconciliationModel struct holds database instance and the number of days
+// either side of a statement line date searched for matching account entries
+type BankReconciliationModel struct {
+	DB             *sql.DB
+	MatchDateRange int
+}
+
+// ImportStatement records the lines of a bank statement for an account and
+// matches them to the account entries. OFX files are read by their extension;
+// anything else is read as CSV with date, description, reference and amount
+// columns where deposits are positive. A header row is skipped
+func (m *BankReconciliationModel) ImportStatement(userID, accountID, fileName string, r io.Reader) (int64, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+			return
+		}
+		_ = tx.Commit()
+	}()
+
+	var lines []models.BankStatementLine
+	closingBalance := ""
+	if strings.EqualFold(filepath.Ext(fileName), ".ofx") {
+		lines, closingBalance, err = parseOFXStatement(r)
+	} else {
+		lines, err = parseCSVStatement(r)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if len(lines) == 0 {
+		err = errors.New("bank statement has no lines")
+		return 0, err
+	}
+
+	statementDate := lines[0].TransactionDate
+	for _, line := range lines {
+		if line.TransactionDate > statementDate {
+			statementDate = line.TransactionDate
+		}
+	}
+
+	sid, err := mysequel.Insert(mysequel.Table{
+		TableName: "bank_statement",
+		Columns:   []string{"user_id", "account_id", "file_name", "statement_date", "closing_balance"},
+		Vals:      []interface{}{userID, accountID, fileName, statementDate, closingBalance},
+		Tx:        tx,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, line := range lines {
+		var lid int64
+		lid, err = mysequel.Insert(mysequel.Table{
+			TableName: "bank_statement_line",
+			Columns:   []string{"bank_statement_id", "account_id", "transaction_date", "description", "reference", "amount"},
+			Vals:      []interface{}{sid, accountID, line.TransactionDate, line.Description, line.Reference, line.Amount},
+			Tx:        tx,
+		})
+		if err != nil {
+			return 0, err
+		}
+
+		err = m.autoMatch(tx, userID, accountID, lid, line)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return sid, nil
+}
+
+// Statement returns the lines of an imported bank statement with their matches
+func (m *BankReconciliationModel) Statement(sid int) ([]models.BankStatementLine, error) {
+	var res []models.BankStatementLine
+	err := mysequel.QueryToStructs(&res, m.DB, queries.BankStatementLines, sid)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// Match manually matches a bank statement line to an account entry of the
+// same account and amount
+func (m *BankReconciliationModel) Match(form url.Values) (int64, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+			return
+		}
+		_ = tx.Commit()
+	}()
+
+	var lineAccountID, entryAccountID int
+	var lineAmount, entryAmount float64
+	var matched sql.NullInt32
+	err = tx.QueryRow(queries.BankStatementLineForUpdate, form.Get("line_id")).Scan(&lineAccountID, &lineAmount, &matched)
+	if err != nil {
+		return 0, err
+	}
+
+	if matched.Valid {
+		err = fmt.Errorf("bank statement line %s is already matched", form.Get("line_id"))
+		return 0, err
+	}
+
+	err = tx.QueryRow(queries.BankAccountEntry, form.Get("account_transaction_id")).Scan(&entryAccountID, &entryAmount, &matched)
+	if err != nil {
+		return 0, err
+	}
+
+	if entryAccountID != lineAccountID {
+		err = errors.New("account entry is not on the account of the bank statement")
+		return 0, err
+	}
+
+	if matched.Valid {
+		err = fmt.Errorf("account entry %s is already matched to bank statement line %d", form.Get("account_transaction_id"), matched.Int32)
+		return 0, err
+	}
+
+	if math.Abs(entryAmount-lineAmount) > 0.005 {
+		err = errors.New("account entry amount does not match the bank statement line")
+		return 0, err
+	}
+
+	err = matchStatementLine(tx, form.Get("user_id"), form.Get("line_id"), form.Get("account_transaction_id"), "Manual")
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.ParseInt(form.Get("line_id"), 10, 64)
+}
+
+// Unmatch removes the match of a bank statement line
+func (m *BankReconciliationModel) Unmatch(form url.Values) (int64, error) {
+	res, err := m.DB.Exec("UPDATE bank_statement_line SET account_transaction_id = NULL, match_type = NULL, matched_by = NULL, matched_on = NULL WHERE id = ? AND account_transaction_id IS NOT NULL", form.Get("line_id"))
+	if err != nil {
+		return 0, err
+	}
+
+	c, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	if c == 0 {
+		return 0, errors.New("bank statement line is not matched")
+	}
+
+	return strconv.ParseInt(form.Get("line_id"), 10, 64)
+}
+
+// Reconciliation returns the bank reconciliation of an account as at a date with
+// the deposits and cheques not on the bank statement and the statement lines not
+// in the books
+func (m *BankReconciliationModel) Reconciliation(accountID int, date string) (models.BankReconciliation, error) {
+	rec := models.BankReconciliation{AccountID: accountID, Date: date}
+	var statementBalance sql.NullFloat64
+	err := m.DB.QueryRow(queries.BankReconciliationBalances, accountID, date, accountID, date).Scan(&rec.BookBalance, &statementBalance)
+	if err != nil {
+		return models.BankReconciliation{}, err
+	}
+
+	err = mysequel.QueryToStructs(&rec.OutstandingDeposits, m.DB, queries.BankUnmatchedEntries, accountID, date, "DR")
+	if err != nil {
+		return models.BankReconciliation{}, err
+	}
+
+	err = mysequel.QueryToStructs(&rec.UnpresentedCheques, m.DB, queries.BankUnmatchedEntries, accountID, date, "CR")
+	if err != nil {
+		return models.BankReconciliation{}, err
+	}
+
+	err = mysequel.QueryToStructs(&rec.UnmatchedStatementLines, m.DB, queries.BankUnmatchedStatementLines, accountID, date)
+	if err != nil {
+		return models.BankReconciliation{}, err
+	}
+
+	balance := rec.BookBalance
+	for _, e := range rec.OutstandingDeposits {
+		balance = balance - e.Amount
+	}
+	for _, e := range rec.UnpresentedCheques {
+		balance = balance + e.Amount
+	}
+	for _, l := range rec.UnmatchedStatementLines {
+		balance = balance + l.Amount
+	}
+	rec.AdjustedBankBalance = math.Round(balance*100) / 100
+
+	if statementBalance.Valid {
+		rec.StatementBalance = &statementBalance.Float64
+		rec.Difference = math.Round((statementBalance.Float64-rec.AdjustedBankBalance)*100) / 100
+	}
+
+	return rec, nil
+}
+
+// autoMatch matches a bank statement line to an unmatched account entry of the same
+// amount, preferring a cheque with the line reference and then the closest date
+func (m *BankReconciliationModel) autoMatch(tx *sql.Tx, userID, accountID string, lid int64, line models.BankStatementLine) error {
+	entryType := "DR"
+	if line.Amount < 0 {
+		entryType = "CR"
+	}
+
+	ref := mysequel.NewNullString(line.Reference)
+	var atid int64
+	err := tx.QueryRow(queries.BankMatchCandidate, accountID, entryType, math.Abs(line.Amount), ref, line.TransactionDate, m.MatchDateRange, line.TransactionDate, m.MatchDateRange, ref, line.TransactionDate).Scan(&atid)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return matchStatementLine(tx, userID, strconv.FormatInt(lid, 10), strconv.FormatInt(atid, 10), "Auto")
+}
+
+// matchStatementLine links a bank statement line to an account entry
+func matchStatementLine(tx *sql.Tx, userID, lid, atid, matchType string) error {
+	_, err := mysequel.Update(mysequel.UpdateTable{
+		Table: mysequel.Table{
+			TableName: "bank_statement_line",
+			Columns:   []string{"account_transaction_id", "match_type", "matched_by", "matched_on"},
+			Vals:      []interface{}{atid, matchType, userID, time.Now().Format("2006-01-02 15:04:05")},
+			Tx:        tx,
+		},
+		WColumns: []string{"id"},
+		WVals:    []string{lid},
+	})
+	return err
+}
+
+// parseCSVStatement reads bank statement lines from a CSV file
+func parseCSVStatement(r io.Reader) ([]models.BankStatementLine, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []models.BankStatementLine
+	for i, record := range records {
+		if len(record) < 4 {
+			return nil, fmt.Errorf("line %d does not have a date, description, reference and amount", i+1)
+		}
+
+		amount, err := strconv.ParseFloat(strings.ReplaceAll(strings.TrimSpace(record[3]), ",", ""), 64)
+		if err != nil && i == 0 {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid amount", i+1)
+		}
+
+		date, err := time.Parse("2006-01-02", strings.TrimSpace(record[0]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid date", i+1)
+		}
+
+		lines = append(lines, models.BankStatementLine{TransactionDate: date.Format("2006-01-02"), Description: strings.TrimSpace(record[1]), Reference: strings.TrimSpace(record[2]), Amount: amount})
+	}
+
+	return lines, nil
+}
+
+var (
+	ofxTransaction    = regexp.MustCompile(`(?i)<STMTTRN>`)
+	ofxTransactionEnd = regexp.MustCompile(`(?i)</STMTTRN>|</BANKTRANLIST>`)
+	ofxLedgerBal      = regexp.MustCompile(`(?is)<LEDGERBAL>.*?<BALAMT>\s*([^<\s]+)`)
+)
+
+// ofxValue returns the value of an OFX element which may not be closed
+func ofxValue(block, tag string) string {
+	re := regexp.MustCompile(`(?i)<` + tag + `>\s*([^<\r\n]*)`)
+	m := re.FindStringSubmatch(block)
+	if m == nil {
+		return ""
+	}
+	return strings.TrimSpace(m[1])
+}
+
+// parseOFXStatement reads bank statement lines and the ledger balance from an OFX file
+func parseOFXStatement(r io.Reader) ([]models.BankStatementLine, string, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, "", err
+	}
+	content := string(b)
+
+	var lines []models.BankStatementLine
+	blocks := ofxTransaction.Split(content, -1)
+	for i := 1; i < len(blocks); i++ {
+		block := blocks[i]
+		if end := ofxTransactionEnd.FindStringIndex(block); end != nil {
+			block = block[:end[0]]
+		}
+
+		amount, err := strconv.ParseFloat(ofxValue(block, "TRNAMT"), 64)
+		if err != nil {
+			return nil, "", fmt.Errorf("transaction %d: invalid amount", i)
+		}
+
+		posted := ofxValue(block, "DTPOSTED")
+		if len(posted) < 8 {
+			return nil, "", fmt.Errorf("transaction %d: invalid date", i)
+		}
+		date, err := time.Parse("20060102", posted[:8])
+		if err != nil {
+			return nil, "", fmt.Errorf("transaction %d: invalid date", i)
+		}
+
+		reference := ofxValue(block, "CHECKNUM")
+		if reference == "" {
+			reference = ofxValue(block, "REFNUM")
+		}
+		if reference == "" {
+			reference = ofxValue(block, "FITID")
+		}
+
+		description := strings.TrimSpace(ofxValue(block, "NAME") + " " + ofxValue(block, "MEMO"))
+		lines = append(lines, models.BankStatementLine{TransactionDate: date.Format("2006-01-02"), Description: description, Reference: reference, Amount: amount})
+	}
+
+	closingBalance := ""
+	if m := ofxLedgerBal.FindStringSubmatch(content); m != nil {
+		closingBalance = m[1]
+	}
+
+	return lines, closingBalance, nil
+}
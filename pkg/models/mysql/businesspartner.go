@@ -4,7 +4,12 @@ import (
 	"database/sql"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"math"
 	"net/url"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/ssrdive/basara/pkg/models"
@@ -75,6 +80,22 @@ func (m *BusinessPartnerModel) Create(rparams, oparams []string, form url.Values
 	return id, nil
 }
 
+// SetReceiptTolerance creates or updates the quantity and price tolerances applied
+// when receiving goods from a supplier against a purchase order
+func (m *BusinessPartnerModel) SetReceiptTolerance(form url.Values) (int64, error) {
+	action := form.Get("action")
+	if action != "Reject" && action != "Flag" {
+		return 0, errors.New("invalid tolerance action")
+	}
+
+	res, err := m.DB.Exec("INSERT INTO supplier_receipt_tolerance (supplier_id, qty_tolerance, price_tolerance, action) VALUES (?, ?, ?, ?) ON DUPLICATE KEY UPDATE qty_tolerance = VALUES(qty_tolerance), price_tolerance = VALUES(price_tolerance), action = VALUES(action)", form.Get("supplier_id"), form.Get("qty_tolerance"), form.Get("price_tolerance"), action)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
 // All returns all items
 func (m *BusinessPartnerModel) All() ([]models.AllItemItem, error) {
 	var res []models.AllItemItem
@@ -150,6 +171,38 @@ func (m *BusinessPartnerModel) BPBalanceDetail(bpID int) ([]models.BusinessPartn
 	return res, nil
 }
 
+// Statement returns the statement of account of a business partner for a period
+// starting with the balance brought forward and a running balance on each entry
+func (m *BusinessPartnerModel) Statement(bpID int, startDate, endDate string) (models.BusinessPartnerStatement, error) {
+	statement := models.BusinessPartnerStatement{BusinessPartnerID: bpID, StartDate: startDate, EndDate: endDate}
+	err := m.DB.QueryRow(queries.BusinessPartnerOpeningBalance, startDate, bpID).Scan(&statement.BusinessPartner, &statement.OpeningBalance)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.BusinessPartnerStatement{}, models.ErrNoRecord
+	} else if err != nil {
+		return models.BusinessPartnerStatement{}, err
+	}
+
+	var details []models.BusinessPartnerBalanceDetail
+	err = mysequel.QueryToStructs(&details, m.DB, queries.BusinessPartnerStatement, bpID, startDate, endDate)
+	if err != nil {
+		return models.BusinessPartnerStatement{}, err
+	}
+
+	balance := statement.OpeningBalance
+	for _, detail := range details {
+		if detail.Type == "DR" {
+			balance = balance + detail.Amount
+		} else {
+			balance = balance - detail.Amount
+		}
+		balance = math.Round(balance*100) / 100
+		statement.Entries = append(statement.Entries, models.BusinessPartnerStatementEntry{BusinessPartnerBalanceDetail: detail, Balance: balance})
+	}
+	statement.ClosingBalance = balance
+
+	return statement, nil
+}
+
 func (m *BusinessPartnerModel) Payment(userID, postingDate, fromAccountID, amount, entries, remark, effectiveDate, checkNumber string) (int64, error) {
 	tx, err := m.DB.Begin()
 	if err != nil {
@@ -163,20 +216,15 @@ func (m *BusinessPartnerModel) Payment(userID, postingDate, fromAccountID, amoun
 		_ = tx.Commit()
 	}()
 
-	err = validatePostingDate(postingDate)
+	var bpPayments []models.BPPaymentEntry
+	_ = json.Unmarshal([]byte(entries), &bpPayments)
+
+	paidAmount, err := strconv.ParseFloat(amount, 64)
 	if err != nil {
 		return 0, err
 	}
 
-	var bpPayments []models.BPPaymentEntry
-	_ = json.Unmarshal([]byte(entries), &bpPayments)
-
-	tid, err := mysequel.Insert(mysequel.Table{
-		TableName: "transaction",
-		Columns:   []string{"user_id", "datetime", "posting_date", "remark"},
-		Vals:      []interface{}{userID, time.Now().Format("2006-01-02 15:04:05"), postingDate, remark},
-		Tx:        tx,
-	})
+	tid, err := createTransaction(tx, userID, postingDate, remark)
 	if err != nil {
 		_ = tx.Rollback()
 		return 0, err
@@ -193,10 +241,85 @@ func (m *BusinessPartnerModel) Payment(userID, postingDate, fromAccountID, amoun
 		return 0, err
 	}
 
+	if checkNumber != "" {
+		_, err = mysequel.Insert(mysequel.Table{
+			TableName: "cheque",
+			Columns:   []string{"user_id", "transaction_id", "cheque_number", "account_id", "issue_date", "effective_date", "amount", "status"},
+			Vals:      []interface{}{userID, tid, checkNumber, fromAccountID, postingDate, effectiveDate, amount, "Issued"},
+			Tx:        tx,
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	// Foreign currency balances are settled at their carrying value and the
+	// difference to the amount paid is a realised exchange gain or loss
+	exchangeDifference := 0.0
+	for _, bpPayment := range bpPayments {
+		var entryAmount float64
+		entryAmount, err = strconv.ParseFloat(bpPayment.Amount, 64)
+		if err != nil {
+			return 0, err
+		}
+
+		currencyCode := strings.ToUpper(bpPayment.CurrencyCode)
+		foreignAmount := entryAmount
+		carryingAmount := entryAmount
+		if currencyCode == "" {
+			currencyCode = BaseCurrency
+		}
+
+		if currencyCode != BaseCurrency {
+			foreignAmount, err = strconv.ParseFloat(bpPayment.ForeignAmount, 64)
+			if err != nil || foreignAmount <= 0 {
+				err = errors.New("foreign amount is required for foreign currency payments")
+				return 0, err
+			}
+
+			var baseBalance, foreignBalance float64
+			err = tx.QueryRow(queries.BusinessPartnerForeignCarrying, bpPayment.BP, currencyCode).Scan(&baseBalance, &foreignBalance)
+			if err != nil {
+				return 0, err
+			}
+
+			if foreignBalance >= 0 {
+				err = fmt.Errorf("business partner %s has no %s balance to pay", bpPayment.BP, currencyCode)
+				return 0, err
+			}
+
+			carryingAmount = math.Round(foreignAmount*(baseBalance/foreignBalance)*100) / 100
+			exchangeDifference = exchangeDifference + (entryAmount - carryingAmount)
+		}
+
+		_, err = mysequel.Insert(mysequel.Table{
+			TableName: "business_partner_financial",
+			Columns:   []string{"effective_date", "business_partner_id", "type", "amount", "transaction_id", "currency_code", "foreign_amount", "exchange_rate"},
+			Vals:      []interface{}{effectiveDate, bpPayment.BP, "DR", carryingAmount, tid, currencyCode, foreignAmount, entryAmount / foreignAmount},
+			Tx:        tx,
+		})
+		if err != nil {
+			_ = tx.Rollback()
+			return 0, err
+		}
+
+		err = allocatePayment(tx, userID, tid, bpPayment.BP, currencyCode, foreignAmount, bpPayment.Allocations)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	exchangeDifference = math.Round(exchangeDifference*100) / 100
+
+	payableAccountID, err := payableAccount(tx)
+	if err != nil {
+		return 0, err
+	}
+
 	_, err = mysequel.Insert(mysequel.Table{
 		TableName: "account_transaction",
 		Columns:   []string{"transaction_id", "account_id", "type", "amount"},
-		Vals:      []interface{}{tid, PayableAccountID, "DR", amount},
+		Vals:      []interface{}{tid, payableAccountID, "DR", fmt.Sprintf("%f", paidAmount-exchangeDifference)},
 		Tx:        tx,
 	})
 	if err != nil {
@@ -204,15 +327,25 @@ func (m *BusinessPartnerModel) Payment(userID, postingDate, fromAccountID, amoun
 		return 0, err
 	}
 
-	for _, bpPayment := range bpPayments {
+	if exchangeDifference != 0 {
+		var exchangeAccountID int
+		exchangeAccountID, err = requirePostingAccount(tx, PostingRuleExchangeGainLoss, "", "")
+		if err != nil {
+			return 0, err
+		}
+
+		entryType := "DR"
+		if exchangeDifference < 0 {
+			entryType = "CR"
+		}
+
 		_, err = mysequel.Insert(mysequel.Table{
-			TableName: "business_partner_financial",
-			Columns:   []string{"effective_date", "business_partner_id", "type", "amount", "transaction_id"},
-			Vals:      []interface{}{effectiveDate, bpPayment.BP, "DR", bpPayment.Amount, tid},
+			TableName: "account_transaction",
+			Columns:   []string{"transaction_id", "account_id", "type", "amount"},
+			Vals:      []interface{}{tid, exchangeAccountID, entryType, fmt.Sprintf("%f", math.Abs(exchangeDifference))},
 			Tx:        tx,
 		})
 		if err != nil {
-			_ = tx.Rollback()
 			return 0, err
 		}
 	}
@@ -220,28 +353,300 @@ func (m *BusinessPartnerModel) Payment(userID, postingDate, fromAccountID, amoun
 	return tid, nil
 }
 
-func validatePostingDate(postingDate string) error {
-	now := time.Now()
+// OpenItems returns the goods received notes of a supplier that are invoiced and
+// not fully paid, and the payments held on account
+func (m *BusinessPartnerModel) OpenItems(bpID int) (models.SupplierOpenItems, error) {
+	var items []models.SupplierOpenItem
+	err := mysequel.QueryToStructs(&items, m.DB, queries.SupplierOpenItems, bpID)
+	if err != nil {
+		return models.SupplierOpenItems{}, err
+	}
+
+	var onAccount []models.SupplierOnAccount
+	err = mysequel.QueryToStructs(&onAccount, m.DB, queries.SupplierOnAccount, bpID)
+	if err != nil {
+		return models.SupplierOpenItems{}, err
+	}
 
-	var m time.Month
-	year, m, _ := now.Date()
-	month := int(m)
+	return models.SupplierOpenItems{BusinessPartnerID: bpID, OnAccount: onAccount, Items: items}, nil
+}
 
-	var oldestDate time.Time
-	if month < 4 {
-		oldestDate = time.Date(year-1, 4, 1, 0, 0, 0, 0, time.UTC)
-	} else {
-		oldestDate = time.Date(year, 4, 1, 0, 0, 0, 0, time.UTC)
+// AllocatePayment allocates amounts paid on account to goods received notes of a supplier
+func (m *BusinessPartnerModel) AllocatePayment(form url.Values) (int64, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
 	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+			return
+		}
+		_ = tx.Commit()
+	}()
 
-	parsedDate, err := time.Parse("2006-01-02", postingDate)
+	var allocations []models.BPPaymentAllocation
+	err = json.Unmarshal([]byte(form.Get("allocations")), &allocations)
 	if err != nil {
-		return errors.New("invalid posting date")
+		return 0, err
 	}
 
-	if parsedDate.Before(oldestDate) {
-		return errors.New("posting date does not fall within the financial year")
-	} else {
+	currencyCode := strings.ToUpper(form.Get("currency_code"))
+	if currencyCode == "" {
+		currencyCode = BaseCurrency
+	}
+
+	var bpID int
+	err = tx.QueryRow("SELECT id FROM business_partner WHERE id = ? FOR UPDATE", form.Get("business_partner_id")).Scan(&bpID)
+	if err != nil {
+		return 0, err
+	}
+
+	var onAccount float64
+	err = tx.QueryRow(queries.SupplierOnAccountBalance, form.Get("business_partner_id"), currencyCode).Scan(&onAccount)
+	if err != nil {
+		return 0, err
+	}
+
+	allocated, err := allocateToGoodsReceivedNotes(tx, form.Get("user_id"), "", form.Get("business_partner_id"), currencyCode, allocations)
+	if err != nil {
+		return 0, err
+	}
+
+	if allocated > onAccount+0.005 {
+		err = fmt.Errorf("allocations exceed the %s amount on account of %.2f", currencyCode, onAccount)
+		return 0, err
+	}
+
+	return mysequel.Insert(mysequel.Table{
+		TableName: "business_partner_payment_allocation",
+		Columns:   []string{"user_id", "business_partner_id", "currency_code", "amount"},
+		Vals:      []interface{}{form.Get("user_id"), form.Get("business_partner_id"), currencyCode, -allocated},
+		Tx:        tx,
+	})
+}
+
+// allocatePayment allocates a payment made to a supplier to goods received notes.
+// Any amount not allocated is held on account
+func allocatePayment(tx *sql.Tx, userID string, tid int64, bpID, currencyCode string, amount float64, allocations []models.BPPaymentAllocation) error {
+	allocated, err := allocateToGoodsReceivedNotes(tx, userID, tid, bpID, currencyCode, allocations)
+	if err != nil {
+		return err
+	}
+
+	unallocated := math.Round((amount-allocated)*100) / 100
+	if unallocated < 0 {
+		return fmt.Errorf("allocations to business partner %s exceed the amount paid", bpID)
+	}
+
+	if unallocated == 0 {
 		return nil
 	}
+
+	_, err = mysequel.Insert(mysequel.Table{
+		TableName: "business_partner_payment_allocation",
+		Columns:   []string{"user_id", "transaction_id", "business_partner_id", "currency_code", "amount"},
+		Vals:      []interface{}{userID, tid, bpID, currencyCode, unallocated},
+		Tx:        tx,
+	})
+	return err
+}
+
+// allocateToGoodsReceivedNotes records allocations against the open amounts of
+// goods received notes of a supplier and returns the total allocated
+func allocateToGoodsReceivedNotes(tx *sql.Tx, userID string, tid interface{}, bpID, currencyCode string, allocations []models.BPPaymentAllocation) (float64, error) {
+	total := 0.0
+	for _, allocation := range allocations {
+		amount, err := strconv.ParseFloat(allocation.Amount, 64)
+		if err != nil || amount <= 0 {
+			return 0, fmt.Errorf("invalid allocation amount for goods received note %s", allocation.GRNID)
+		}
+
+		// The goods received note is locked so that concurrent payments
+		// and allocations cannot both allocate its open amount
+		var grnID int
+		err = tx.QueryRow("SELECT id FROM goods_received_note WHERE id = ? FOR UPDATE", allocation.GRNID).Scan(&grnID)
+		if err == sql.ErrNoRows {
+			return 0, fmt.Errorf("goods received note %s does not exist", allocation.GRNID)
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		var item models.SupplierOpenItem
+		err = tx.QueryRow(queries.SupplierOpenItem, bpID, allocation.GRNID).Scan(&item.GRNID, &item.EffectiveDate, &item.CurrencyCode, &item.InvoiceNumbers, &item.Invoiced, &item.Returned, &item.Allocated, &item.OpenAmount)
+		if err == sql.ErrNoRows {
+			return 0, fmt.Errorf("goods received note %s has no invoiced payable for business partner %s", allocation.GRNID, bpID)
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		if item.CurrencyCode != currencyCode {
+			return 0, fmt.Errorf("goods received note %s is in %s", allocation.GRNID, item.CurrencyCode)
+		}
+
+		if amount > item.OpenAmount+0.005 {
+			return 0, fmt.Errorf("allocation to goods received note %s exceeds the open amount of %.2f", allocation.GRNID, item.OpenAmount)
+		}
+
+		_, err = mysequel.Insert(mysequel.Table{
+			TableName: "business_partner_payment_allocation",
+			Columns:   []string{"user_id", "transaction_id", "business_partner_id", "goods_received_note_id", "currency_code", "amount"},
+			Vals:      []interface{}{userID, tid, bpID, allocation.GRNID, currencyCode, amount},
+			Tx:        tx,
+		})
+		if err != nil {
+			return 0, err
+		}
+
+		total = total + amount
+	}
+
+	return math.Round(total*100) / 100, nil
+}
+
+// Aging returns the balances of business partners as at a date split into aging
+// buckets by the effective date of the goods received notes and payments that remain open
+func (m *BusinessPartnerModel) Aging(date string) ([]models.BusinessPartnerAging, error) {
+	items, err := m.agingItems(date, "")
+	if err != nil {
+		return nil, err
+	}
+
+	var res []models.BusinessPartnerAging
+	for _, item := range items {
+		if len(res) == 0 || res[len(res)-1].ID != item.BusinessPartnerID {
+			res = append(res, models.BusinessPartnerAging{ID: item.BusinessPartnerID, Name: item.BusinessPartner})
+		}
+
+		aging := &res[len(res)-1]
+		switch item.Bucket {
+		case "current":
+			aging.Current = math.Round((aging.Current+item.OpenAmount)*100) / 100
+		case "1-30":
+			aging.Days1To30 = math.Round((aging.Days1To30+item.OpenAmount)*100) / 100
+		case "31-60":
+			aging.Days31To60 = math.Round((aging.Days31To60+item.OpenAmount)*100) / 100
+		case "61-90":
+			aging.Days61To90 = math.Round((aging.Days61To90+item.OpenAmount)*100) / 100
+		default:
+			aging.Over90 = math.Round((aging.Over90+item.OpenAmount)*100) / 100
+		}
+		aging.Balance = math.Round((aging.Balance+item.OpenAmount)*100) / 100
+	}
+
+	return res, nil
+}
+
+// AgingDetail returns the goods received notes open for a business partner and the
+// amounts held on account as at a date with their aging bucket
+func (m *BusinessPartnerModel) AgingDetail(bpID int, date string) ([]models.BusinessPartnerAgingItem, error) {
+	return m.agingItems(date, strconv.Itoa(bpID))
+}
+
+// agingItems returns the goods received notes left open by supplier invoices, returns
+// and payment allocations, and the payments held on account with allocations from
+// account applied oldest first, matching the supplier open items. Open amounts are in
+// the base currency and follow the balance convention of debit less credit
+func (m *BusinessPartnerModel) agingItems(date, bpID string) ([]models.BusinessPartnerAgingItem, error) {
+	asAt, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return nil, errors.New("invalid aging date")
+	}
+
+	b := mysequel.NewNullString(bpID)
+
+	var grnEntries []models.BusinessPartnerAgingEntry
+	err = mysequel.QueryToStructs(&grnEntries, m.DB, queries.BusinessPartnerAgingItems, date, date, date, b, b)
+	if err != nil {
+		return nil, err
+	}
+
+	var onAccountEntries []models.BusinessPartnerAgingEntry
+	err = mysequel.QueryToStructs(&onAccountEntries, m.DB, queries.BusinessPartnerAgingOnAccount, date, b, b)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []models.BusinessPartnerAgingItem
+	for _, entry := range grnEntries {
+		res = append(res, models.BusinessPartnerAgingItem{BusinessPartnerAgingEntry: entry, ForeignOpenAmount: entry.Amount})
+	}
+
+	var open []models.BusinessPartnerAgingItem
+	for i, entry := range onAccountEntries {
+		if i > 0 && (onAccountEntries[i-1].BusinessPartnerID != entry.BusinessPartnerID || onAccountEntries[i-1].CurrencyCode != entry.CurrencyCode) {
+			res = append(res, open...)
+			open = nil
+		}
+
+		if entry.Amount > 0 {
+			open = append(open, models.BusinessPartnerAgingItem{BusinessPartnerAgingEntry: entry, ForeignOpenAmount: entry.Amount})
+			continue
+		}
+
+		amount := -entry.Amount
+		for len(open) > 0 && amount > 0 {
+			applied := math.Min(open[0].ForeignOpenAmount, amount)
+			open[0].ForeignOpenAmount = math.Round((open[0].ForeignOpenAmount-applied)*100) / 100
+			amount = math.Round((amount-applied)*100) / 100
+
+			if open[0].ForeignOpenAmount == 0 {
+				open = open[1:]
+			}
+		}
+	}
+	res = append(res, open...)
+
+	for i := range res {
+		effectiveDate, err := time.Parse("2006-01-02", res[i].EffectiveDate)
+		if err != nil {
+			return nil, err
+		}
+
+		res[i].OpenAmount = math.Round(res[i].ForeignOpenAmount*res[i].ExchangeRate*100) / 100
+		res[i].Days = int(asAt.Sub(effectiveDate).Hours() / 24)
+		res[i].Bucket = agingBucket(res[i].Days)
+	}
+
+	sort.SliceStable(res, func(i, j int) bool {
+		if res[i].BusinessPartner != res[j].BusinessPartner {
+			return res[i].BusinessPartner < res[j].BusinessPartner
+		}
+		if res[i].BusinessPartnerID != res[j].BusinessPartnerID {
+			return res[i].BusinessPartnerID < res[j].BusinessPartnerID
+		}
+		return res[i].EffectiveDate < res[j].EffectiveDate
+	})
+
+	return res, nil
+}
+
+// agingBucket returns the aging bucket of an entry by its age in days
+func agingBucket(days int) string {
+	switch {
+	case days <= 0:
+		return "current"
+	case days <= 30:
+		return "1-30"
+	case days <= 60:
+		return "31-60"
+	case days <= 90:
+		return "61-90"
+	default:
+		return "90+"
+	}
+}
+
+// ForeignBalances returns the foreign currency balances of business partners and
+// their carrying value in the base currency as at a date
+func (m *BusinessPartnerModel) ForeignBalances(date string) ([]models.BusinessPartnerForeignBalance, error) {
+	var res []models.BusinessPartnerForeignBalance
+	err := mysequel.QueryToStructs(&res, m.DB, queries.BusinessPartnerForeignBalances, BaseCurrency, date)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
 }
@@ -0,0 +1,195 @@
+package mysql
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/ssrdive/basara/pkg/models"
+	"github.com/ssrdive/basara/pkg/sql/queries"
+	"github.com/ssrdive/mysequel"
+)
+
+// ChequeModel struct holds database instance
+type ChequeModel struct {
+	DB *sql.DB
+}
+
+// chequeTransitions holds the statuses a cheque can be moved to from each status.
+// Cleared, bounced and cancelled cheques cannot be changed
+var chequeTransitions = map[string][]string{
+	"Issued":    {"Presented", "Cleared", "Bounced", "Cancelled"},
+	"Presented": {"Cleared", "Bounced"},
+}
+
+// UpdateStatus moves a cheque to a new status. Bounced and cancelled cheques
+// reverse the payment they were issued for on the posting date
+func (m *ChequeModel) UpdateStatus(form url.Values) (int64, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+			return
+		}
+		_ = tx.Commit()
+	}()
+
+	var tid int64
+	var chequeNumber, status string
+	err = tx.QueryRow(queries.ChequeForUpdate, form.Get("cheque_id")).Scan(&tid, &chequeNumber, &status)
+	if err != nil {
+		return 0, err
+	}
+
+	newStatus := form.Get("status")
+	allowed := false
+	for _, s := range chequeTransitions[status] {
+		if s == newStatus {
+			allowed = true
+		}
+	}
+	if !allowed {
+		err = fmt.Errorf("cheque %s cannot be moved from %s to %s", chequeNumber, status, newStatus)
+		return 0, err
+	}
+
+	reversal := ""
+	if newStatus == "Bounced" || newStatus == "Cancelled" {
+		postingDate := form.Get("posting_date")
+		if postingDate == "" {
+			postingDate = time.Now().Format("2006-01-02")
+		}
+
+		var rtid int64
+		rtid, err = reversePayment(tx, form.Get("user_id"), tid, postingDate, fmt.Sprintf("CHEQUE %s %s [TRANSACTION %d]", chequeNumber, newStatus, tid))
+		if err != nil {
+			return 0, err
+		}
+		reversal = strconv.FormatInt(rtid, 10)
+	}
+
+	_, err = mysequel.Update(mysequel.UpdateTable{
+		Table: mysequel.Table{
+			TableName: "cheque",
+			Columns:   []string{"status", "status_changed_by", "status_changed_on", "status_remarks", "reversal_transaction_id"},
+			Vals:      []interface{}{newStatus, form.Get("user_id"), time.Now().Format("2006-01-02 15:04:05"), form.Get("remarks"), reversal},
+			Tx:        tx,
+		},
+		WColumns: []string{"id"},
+		WVals:    []string{form.Get("cheque_id")},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.ParseInt(form.Get("cheque_id"), 10, 64)
+}
+
+// List returns the cheque register filtered by status when one is given
+func (m *ChequeModel) List(status string) ([]models.Cheque, error) {
+	s := mysequel.NewNullString(status)
+
+	var res []models.Cheque
+	err := mysequel.QueryToStructs(&res, m.DB, queries.ChequeList, s, s)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// Maturing returns the outstanding cheques with effective dates within
+// the given number of days from today
+func (m *ChequeModel) Maturing(days int) ([]models.Cheque, error) {
+	today := time.Now()
+
+	var res []models.Cheque
+	err := mysequel.QueryToStructs(&res, m.DB, queries.ChequesMaturing, today.Format("2006-01-02"), today.AddDate(0, 0, days).Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// reversePayment reverses the account entries of a business partner payment
+// and records opposite business_partner_financial entries and allocations
+func reversePayment(tx *sql.Tx, userID string, tid int64, postingDate, remark string) (int64, error) {
+	err := checkPaymentOnAccount(tx, tid)
+	if err != nil {
+		return 0, err
+	}
+
+	rtid, err := reverseTransaction(tx, userID, tid, postingDate, remark)
+	if err != nil {
+		return 0, err
+	}
+
+	var entries []models.BusinessPartnerFinancialReversal
+	err = mysequel.QueryToStructs(&entries, tx, queries.BusinessPartnerFinancialByTransaction, tid)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, entry := range entries {
+		entryType := "DR"
+		if entry.Type == "DR" {
+			entryType = "CR"
+		}
+
+		_, err = mysequel.Insert(mysequel.Table{
+			TableName: "business_partner_financial",
+			Columns:   []string{"effective_date", "business_partner_id", "type", "amount", "transaction_id", "currency_code", "foreign_amount", "exchange_rate"},
+			Vals:      []interface{}{postingDate, entry.BusinessPartnerID, entryType, entry.Amount, rtid, entry.CurrencyCode, entry.ForeignAmount, entry.ExchangeRate},
+			Tx:        tx,
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	_, err = tx.Exec(queries.ReversePaymentAllocations, userID, rtid, tid)
+	if err != nil {
+		return 0, err
+	}
+
+	return rtid, nil
+}
+
+// checkPaymentOnAccount returns an error when the part of a payment held on account
+// has since been allocated to goods received notes, as reversing the payment would
+// leave the business partner with a negative amount on account
+func checkPaymentOnAccount(tx *sql.Tx, tid int64) error {
+	var bpID int
+	var currencyCode string
+	var amount float64
+	err := tx.QueryRow(queries.PaymentOnAccount, tid).Scan(&bpID, &currencyCode, &amount)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	err = tx.QueryRow("SELECT id FROM business_partner WHERE id = ? FOR UPDATE", bpID).Scan(&bpID)
+	if err != nil {
+		return err
+	}
+
+	var onAccount float64
+	err = tx.QueryRow(queries.SupplierOnAccountBalance, bpID, currencyCode).Scan(&onAccount)
+	if err != nil {
+		return err
+	}
+
+	if onAccount+0.005 < amount {
+		return fmt.Errorf("%.2f of the %s %.2f held on account from this payment has since been allocated to goods received notes", amount-onAccount, currencyCode, amount)
+	}
+
+	return nil
+}
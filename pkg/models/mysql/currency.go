@@ -0,0 +1,37 @@
+package mysql
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// BaseCurrency is the currency of the books. Amounts on documents in other
+// currencies are converted to it with the exchange rate captured on the document
+const BaseCurrency = "LKR"
+
+// documentCurrency returns the currency code and exchange rate of a document form.
+// Documents without a currency are in the base currency
+func documentCurrency(form url.Values) (string, float64, error) {
+	return currencyRate(form.Get("currency_code"), form.Get("exchange_rate"))
+}
+
+// currencyRate validates a currency code and its exchange rate to the base currency
+func currencyRate(currencyCode, exchangeRate string) (string, float64, error) {
+	currencyCode = strings.ToUpper(strings.TrimSpace(currencyCode))
+	if currencyCode == "" || currencyCode == BaseCurrency {
+		return BaseCurrency, 1, nil
+	}
+
+	if len(currencyCode) != 3 {
+		return "", 0, errors.New("invalid currency code")
+	}
+
+	rate, err := strconv.ParseFloat(exchangeRate, 64)
+	if err != nil || rate <= 0 {
+		return "", 0, errors.New("exchange rate is required for foreign currency documents")
+	}
+
+	return currencyCode, rate, nil
+}
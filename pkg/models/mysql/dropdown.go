@@ -189,3 +189,30 @@ func (m *DropdownModel) GetGrn() ([]*models.Dropdown, error) {
 	}
 	return items, nil
 }
+
+func (m *DropdownModel) GetCostedGrn() ([]*models.Dropdown, error) {
+	stmt := `SELECT GRN.id,  concat(GRN.id, ' - ', BP.name) as name FROM goods_received_note GRN LEFT JOIN business_partner BP ON BP.id = GRN.supplier_id WHERE landed_cost_id is not null ORDER BY GRN.id DESC`
+	rows, err := m.DB.Query(stmt)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	items := []*models.Dropdown{}
+
+	for rows.Next() {
+		i := &models.Dropdown{}
+
+		err = rows.Scan(&i.ID, &i.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, i)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,284 @@
+package mysql
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ssrdive/basara/pkg/models"
+	"github.com/ssrdive/basara/pkg/sql/queries"
+	"github.com/ssrdive/mysequel"
+	"github.com/ssrdive/scribe"
+	smodels "github.com/ssrdive/scribe/models"
+)
+
+// ExchangeRateModel struct holds database instance
+type ExchangeRateModel struct {
+	DB *sql.DB
+}
+
+// CreateRate records the exchange rate of a currency on a date, replacing the
+// rate already recorded for that date, and returns the id of the exchange rate
+func (m *ExchangeRateModel) CreateRate(form url.Values) (int64, error) {
+	return saveExchangeRate(m.DB, form.Get("user_id"), form.Get("currency_code"), form.Get("rate_date"), form.Get("rate"))
+}
+
+// ImportRates records the exchange rates in a CSV file with currency code, date and
+// rate columns. A first row that does not start with a currency code, or whose date
+// and rate are both invalid, is taken as a header and skipped. Either all rates
+// are recorded or none
+func (m *ExchangeRateModel) ImportRates(userID string, r io.Reader) (int64, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+			return
+		}
+		_ = tx.Commit()
+	}()
+
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return 0, err
+	}
+
+	var count int64
+	for i, record := range records {
+		if len(record) < 3 {
+			err = fmt.Errorf("line %d does not have a currency code, date and rate", i+1)
+			return 0, err
+		}
+
+		if i == 0 && isExchangeRateHeader(record) {
+			continue
+		}
+
+		_, err = saveExchangeRate(tx, userID, record[0], strings.TrimSpace(record[1]), strings.TrimSpace(record[2]))
+		if err != nil {
+			err = fmt.Errorf("line %d: %v", i+1, err)
+			return 0, err
+		}
+		count++
+	}
+
+	return count, nil
+}
+
+// List returns the recorded exchange rates of a currency, or of all currencies
+func (m *ExchangeRateModel) List(currencyCode string) ([]models.ExchangeRate, error) {
+	c := mysequel.NewNullString(strings.ToUpper(currencyCode))
+
+	var res []models.ExchangeRate
+	err := mysequel.QueryToStructs(&res, m.DB, queries.ExchangeRateList, c, c)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// Revalue revalues the open foreign currency balances of business partners at the
+// exchange rates of a month end and posts the unrealised gain or loss against the
+// payable account. The revaluation is reversed on the first day of the next month
+func (m *ExchangeRateModel) Revalue(form url.Values) (int64, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+			return
+		}
+		_ = tx.Commit()
+	}()
+
+	date, err := time.Parse("2006-01-02", form.Get("date"))
+	if err != nil {
+		return 0, err
+	}
+
+	reversalDate := date.AddDate(0, 0, 1)
+	if reversalDate.Day() != 1 {
+		err = errors.New("revaluation date must be the last day of a month")
+		return 0, err
+	}
+
+	userID := form.Get("user_id")
+
+	err = checkPostingPeriod(tx, userID, date.Format("2006-01-02"))
+	if err != nil {
+		return 0, err
+	}
+
+	// The reversal on the first day of the next month falls in the next
+	// financial year when revaluing the last month of a year
+	err = checkPostingPeriod(tx, userID, reversalDate.Format("2006-01-02"))
+	if err != nil {
+		err = fmt.Errorf("revaluation cannot be reversed on %s: %v", reversalDate.Format("2006-01-02"), err)
+		return 0, err
+	}
+
+	exchangeAccountID, err := requirePostingAccount(tx, PostingRuleExchangeGainLoss, "", "")
+	if err != nil {
+		return 0, err
+	}
+
+	payableAccountID, err := payableAccount(tx)
+	if err != nil {
+		return 0, err
+	}
+
+	erid, err := mysequel.Insert(mysequel.Table{
+		TableName: "exchange_revaluation",
+		Columns:   []string{"user_id", "revaluation_date"},
+		Vals:      []interface{}{userID, date.Format("2006-01-02")},
+		Tx:        tx,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var balances []models.BusinessPartnerForeignBalance
+	err = mysequel.QueryToStructs(&balances, tx, queries.BusinessPartnerForeignBalances, BaseCurrency, date.Format("2006-01-02"))
+	if err != nil {
+		return 0, err
+	}
+
+	totalDifference := 0.0
+	for _, balance := range balances {
+		var rate float64
+		rate, err = exchangeRateOn(tx, balance.CurrencyCode, date.Format("2006-01-02"))
+		if err != nil {
+			return 0, err
+		}
+
+		revaluedBalance := math.Round(balance.ForeignBalance*rate*100) / 100
+		difference := math.Round((revaluedBalance-balance.BaseBalance)*100) / 100
+
+		_, err = mysequel.Insert(mysequel.Table{
+			TableName: "exchange_revaluation_item",
+			Columns:   []string{"exchange_revaluation_id", "business_partner_id", "currency_code", "foreign_balance", "base_balance", "exchange_rate", "revalued_balance", "difference"},
+			Vals:      []interface{}{erid, balance.ID, balance.CurrencyCode, balance.ForeignBalance, balance.BaseBalance, rate, revaluedBalance, difference},
+			Tx:        tx,
+		})
+		if err != nil {
+			return 0, err
+		}
+
+		totalDifference = totalDifference + difference
+	}
+
+	totalDifference = math.Round(totalDifference*100) / 100
+	if totalDifference == 0 {
+		return erid, nil
+	}
+
+	tid, err := createTransaction(tx, userID, date.Format("2006-01-02"), fmt.Sprintf("EXCHANGE REVALUATION %d", erid))
+	if err != nil {
+		return 0, err
+	}
+
+	// Supplier balances are credit balances, a fall in the revalued
+	// balance means more is owed and is an unrealised loss
+	var journalEntries []smodels.JournalEntry
+	if totalDifference < 0 {
+		journalEntries = []smodels.JournalEntry{
+			{Account: fmt.Sprintf("%d", exchangeAccountID), Debit: fmt.Sprintf("%f", -totalDifference), Credit: ""},
+			{Account: fmt.Sprintf("%d", payableAccountID), Debit: "", Credit: fmt.Sprintf("%f", -totalDifference)},
+		}
+	} else {
+		journalEntries = []smodels.JournalEntry{
+			{Account: fmt.Sprintf("%d", payableAccountID), Debit: fmt.Sprintf("%f", totalDifference), Credit: ""},
+			{Account: fmt.Sprintf("%d", exchangeAccountID), Debit: "", Credit: fmt.Sprintf("%f", totalDifference)},
+		}
+	}
+	err = scribe.IssueJournalEntries(tx, tid, journalEntries)
+	if err != nil {
+		return 0, err
+	}
+
+	rtid, err := reverseTransaction(tx, userID, tid, reversalDate.Format("2006-01-02"), fmt.Sprintf("EXCHANGE REVALUATION %d REVERSAL", erid))
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec("UPDATE exchange_revaluation SET transaction_id = ?, reversal_transaction_id = ? WHERE id = ?", tid, rtid, erid)
+	if err != nil {
+		return 0, err
+	}
+
+	return erid, nil
+}
+
+// exchangeRateOn returns the latest exchange rate of a currency on or before a date
+func exchangeRateOn(tx *sql.Tx, currencyCode, date string) (float64, error) {
+	if currencyCode == BaseCurrency {
+		return 1, nil
+	}
+
+	var rate float64
+	err := tx.QueryRow(queries.ExchangeRateOn, currencyCode, date).Scan(&rate)
+	if err == sql.ErrNoRows {
+		return 0, fmt.Errorf("no %s exchange rate on or before %s", currencyCode, date)
+	}
+
+	return rate, err
+}
+
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// isExchangeRateHeader reports whether a CSV row is a column header rather than
+// an exchange rate, so that a mistyped date or rate on the first row is reported
+// instead of being skipped
+func isExchangeRateHeader(record []string) bool {
+	code := strings.TrimSpace(record[0])
+	if len(code) != 3 {
+		return true
+	}
+	for _, c := range code {
+		if (c < 'A' || c > 'Z') && (c < 'a' || c > 'z') {
+			return true
+		}
+	}
+
+	_, derr := time.Parse("2006-01-02", strings.TrimSpace(record[1]))
+	_, rerr := strconv.ParseFloat(strings.TrimSpace(record[2]), 64)
+	return derr != nil && rerr != nil
+}
+
+// saveExchangeRate records or replaces the exchange rate of a currency on a date
+// and returns the id of the exchange rate
+func saveExchangeRate(db execer, userID, currencyCode, rateDate, rate string) (int64, error) {
+	currencyCode, exchangeRate, err := currencyRate(currencyCode, rate)
+	if err != nil {
+		return 0, err
+	}
+
+	if currencyCode == BaseCurrency {
+		return 0, errors.New("exchange rates are recorded for foreign currencies only")
+	}
+
+	if _, err = time.Parse("2006-01-02", rateDate); err != nil {
+		return 0, err
+	}
+
+	res, err := db.Exec("INSERT INTO exchange_rate (user_id, currency_code, rate_date, rate) VALUES (?, ?, ?, ?) ON DUPLICATE KEY UPDATE id = LAST_INSERT_ID(id), user_id = VALUES(user_id), rate = VALUES(rate)", userID, currencyCode, rateDate, exchangeRate)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.LastInsertId()
+}
@@ -0,0 +1,490 @@
+package mysql
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/ssrdive/basara/pkg/models"
+	"github.com/ssrdive/basara/pkg/sql/queries"
+	"github.com/ssrdive/mysequel"
+	"github.com/ssrdive/scribe"
+	smodels "github.com/ssrdive/scribe/models"
+	squeries "github.com/ssrdive/scribe/queries"
+)
+
+// Financial period statuses. Soft closed periods accept postings only from
+// users with the period override permission
+const (
+	PeriodOpen       = "Open"
+	PeriodSoftClosed = "SoftClosed"
+	PeriodClosed     = "Closed"
+)
+
+// FinancialPeriodModel struct holds database instance
+type FinancialPeriodModel struct {
+	DB *sql.DB
+}
+
+// CreateYear creates a financial year of twelve monthly periods starting on the first
+// day of the given month. Financial years cannot overlap and only users with the period
+// override permission can create them
+func (m *FinancialPeriodModel) CreateYear(form url.Values) (int64, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+			return
+		}
+		_ = tx.Commit()
+	}()
+
+	err = requirePeriodOverride(tx, form.Get("user_id"), "creating a financial year")
+	if err != nil {
+		return 0, err
+	}
+
+	startDate, err := time.Parse("2006-01-02", form.Get("start_date"))
+	if err != nil {
+		return 0, errors.New("invalid financial year start date")
+	}
+
+	if startDate.Day() != 1 {
+		err = errors.New("financial year must start on the first day of a month")
+		return 0, err
+	}
+	endDate := startDate.AddDate(1, 0, -1)
+
+	var overlapping int
+	err = tx.QueryRow(queries.FinancialYearOverlap, endDate.Format("2006-01-02"), startDate.Format("2006-01-02")).Scan(&overlapping)
+	if err != nil {
+		return 0, err
+	}
+
+	if overlapping > 0 {
+		err = errors.New("financial year overlaps an existing financial year")
+		return 0, err
+	}
+
+	name := form.Get("name")
+	if name == "" {
+		name = fmt.Sprintf("%d/%d", startDate.Year(), endDate.Year())
+	}
+
+	fyid, err := mysequel.Insert(mysequel.Table{
+		TableName: "financial_year",
+		Columns:   []string{"user_id", "name", "start_date", "end_date"},
+		Vals:      []interface{}{form.Get("user_id"), name, startDate.Format("2006-01-02"), endDate.Format("2006-01-02")},
+		Tx:        tx,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for i := 0; i < 12; i++ {
+		periodStart := startDate.AddDate(0, i, 0)
+		_, err = mysequel.Insert(mysequel.Table{
+			TableName: "financial_period",
+			Columns:   []string{"financial_year_id", "name", "start_date", "end_date"},
+			Vals:      []interface{}{fyid, periodStart.Format("Jan 2006"), periodStart.Format("2006-01-02"), periodStart.AddDate(0, 1, -1).Format("2006-01-02")},
+			Tx:        tx,
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return fyid, nil
+}
+
+// SetPeriodStatus opens, soft closes or closes a financial period. Only users with
+// the period override permission can change the status of a period. The periods of a
+// closed year cannot be changed and those of a reopened year cannot be opened again
+// as the year has been audited
+func (m *FinancialPeriodModel) SetPeriodStatus(form url.Values) (int64, error) {
+	status := form.Get("status")
+	if status != PeriodOpen && status != PeriodSoftClosed && status != PeriodClosed {
+		return 0, fmt.Errorf("invalid financial period status %s", status)
+	}
+
+	err := requirePeriodOverride(m.DB, form.Get("user_id"), "changing the status of a financial period")
+	if err != nil {
+		return 0, err
+	}
+
+	var closed, reopened int
+	err = m.DB.QueryRow(queries.FinancialPeriodYearClosings, form.Get("period_id")).Scan(&closed, &reopened)
+	if err != nil {
+		return 0, err
+	}
+
+	if closed > 0 {
+		return 0, errors.New("financial year of the period is closed")
+	}
+
+	if reopened > 0 && status == PeriodOpen {
+		return 0, errors.New("periods of a reopened financial year cannot be opened")
+	}
+
+	res, err := m.DB.Exec(queries.SetFinancialPeriodStatus, status, form.Get("user_id"), form.Get("period_id"))
+	if err != nil {
+		return 0, err
+	}
+
+	c, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	if c == 0 {
+		return 0, errors.New("financial period does not exist or its financial year is closed")
+	}
+
+	return strconv.ParseInt(form.Get("period_id"), 10, 64)
+}
+
+// Years returns the financial years
+func (m *FinancialPeriodModel) Years() ([]models.FinancialYear, error) {
+	var res []models.FinancialYear
+	err := mysequel.QueryToStructs(&res, m.DB, queries.FinancialYears)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// Periods returns the periods of a financial year
+func (m *FinancialPeriodModel) Periods(fyid int) ([]models.FinancialPeriod, error) {
+	var res []models.FinancialPeriod
+	err := mysequel.QueryToStructs(&res, m.DB, queries.FinancialPeriods, fyid)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// ClosingPreview returns the closing journal of a financial year without posting it
+func (m *FinancialPeriodModel) ClosingPreview(fyid int) (models.YearEndClosing, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return models.YearEndClosing{}, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	closing, err := yearEndClosing(tx, fyid, queries.FinancialYear)
+	if err == sql.ErrNoRows {
+		return models.YearEndClosing{}, models.ErrNoRecord
+	}
+
+	return closing, err
+}
+
+// CloseYear posts the closing journal of a financial year on its last day, transferring
+// the income and expense balances to retained earnings, and closes the year and its periods.
+// Only users with the period override permission can close a year and only once it has ended
+func (m *FinancialPeriodModel) CloseYear(form url.Values) (int64, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+			return
+		}
+		_ = tx.Commit()
+	}()
+
+	fyid, err := strconv.Atoi(form.Get("financial_year_id"))
+	if err != nil {
+		return 0, err
+	}
+
+	userID := form.Get("user_id")
+	err = requirePeriodOverride(tx, userID, "closing a financial year")
+	if err != nil {
+		return 0, err
+	}
+
+	closing, err := yearEndClosing(tx, fyid, queries.FinancialYearForUpdate)
+	if err != nil {
+		return 0, err
+	}
+
+	if closing.Status == PeriodClosed {
+		err = fmt.Errorf("financial year %s is already closed", closing.Name)
+		return 0, err
+	}
+
+	if time.Now().Format("2006-01-02") <= closing.EndDate {
+		err = fmt.Errorf("financial year %s has not ended yet", closing.Name)
+		return 0, err
+	}
+
+	tid := ""
+	if len(closing.Entries) > 0 {
+		var journalEntries []smodels.JournalEntry
+		for _, entry := range closing.Entries {
+			if entry.Debit != 0 {
+				journalEntries = append(journalEntries, smodels.JournalEntry{Account: fmt.Sprintf("%d", entry.AccountID), Debit: fmt.Sprintf("%f", entry.Debit), Credit: ""})
+			} else {
+				journalEntries = append(journalEntries, smodels.JournalEntry{Account: fmt.Sprintf("%d", entry.AccountID), Debit: "", Credit: fmt.Sprintf("%f", entry.Credit)})
+			}
+		}
+		if closing.NetProfit > 0 {
+			journalEntries = append(journalEntries, smodels.JournalEntry{Account: fmt.Sprintf("%d", closing.RetainedEarningsAccountID), Debit: "", Credit: fmt.Sprintf("%f", closing.NetProfit)})
+		} else if closing.NetProfit < 0 {
+			journalEntries = append(journalEntries, smodels.JournalEntry{Account: fmt.Sprintf("%d", closing.RetainedEarningsAccountID), Debit: fmt.Sprintf("%f", -closing.NetProfit), Credit: ""})
+		}
+
+		// The closing journal is posted on the last day of the year regardless of
+		// the status of its period as it is the posting that closes the year
+		var ctid int64
+		ctid, err = insertTransaction(tx, userID, closing.EndDate, fmt.Sprintf("YEAR END CLOSING %s", closing.Name))
+		if err != nil {
+			return 0, err
+		}
+
+		err = scribe.IssueJournalEntries(tx, ctid, journalEntries)
+		if err != nil {
+			return 0, err
+		}
+		tid = strconv.FormatInt(ctid, 10)
+	}
+
+	cid, err := mysequel.Insert(mysequel.Table{
+		TableName: "financial_year_closing",
+		Columns:   []string{"financial_year_id", "user_id", "net_profit", "transaction_id"},
+		Vals:      []interface{}{fyid, userID, closing.NetProfit, tid},
+		Tx:        tx,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec("UPDATE financial_year SET status = ? WHERE id = ?", PeriodClosed, fyid)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(queries.SetFinancialYearPeriodsStatus, PeriodClosed, userID, fyid)
+	if err != nil {
+		return 0, err
+	}
+
+	return cid, nil
+}
+
+// ReopenYear reopens a closed financial year for auditors' adjustments by reversing
+// its closing journal. The periods of the year are soft closed so only users with
+// the period override permission can post to them, they cannot be opened again, and
+// the year must be closed again
+func (m *FinancialPeriodModel) ReopenYear(form url.Values) (int64, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+			return
+		}
+		_ = tx.Commit()
+	}()
+
+	userID := form.Get("user_id")
+	fyid := form.Get("financial_year_id")
+
+	err = requirePeriodOverride(tx, userID, "reopening a financial year")
+	if err != nil {
+		return 0, err
+	}
+
+	var name, startDate, endDate, status string
+	err = tx.QueryRow(queries.FinancialYearForUpdate, fyid).Scan(&name, &startDate, &endDate, &status)
+	if err != nil {
+		return 0, err
+	}
+
+	if status != PeriodClosed {
+		err = fmt.Errorf("financial year %s is not closed", name)
+		return 0, err
+	}
+
+	var cid, tid int64
+	err = tx.QueryRow(queries.OpenFinancialYearClosing, fyid).Scan(&cid, &tid)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec("UPDATE financial_year SET status = ? WHERE id = ?", PeriodOpen, fyid)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(queries.SetFinancialYearPeriodsStatus, PeriodSoftClosed, userID, fyid)
+	if err != nil {
+		return 0, err
+	}
+
+	rtid := ""
+	if tid != 0 {
+		var r int64
+		r, err = reverseTransaction(tx, userID, tid, endDate, fmt.Sprintf("YEAR END CLOSING %s REOPENED [TRANSACTION %d]", name, tid))
+		if err != nil {
+			return 0, err
+		}
+		rtid = strconv.FormatInt(r, 10)
+	}
+
+	_, err = mysequel.Update(mysequel.UpdateTable{
+		Table: mysequel.Table{
+			TableName: "financial_year_closing",
+			Columns:   []string{"reopened_by", "reopened_on", "reopen_remarks", "reversal_transaction_id"},
+			Vals:      []interface{}{userID, time.Now().Format("2006-01-02 15:04:05"), form.Get("remarks"), rtid},
+			Tx:        tx,
+		},
+		WColumns: []string{"id"},
+		WVals:    []string{strconv.FormatInt(cid, 10)},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return cid, nil
+}
+
+// Validate checks that a financial period covers the current date so that a missing
+// financial year is noticed before postings start failing
+func (m *FinancialPeriodModel) Validate() error {
+	today := time.Now().Format("2006-01-02")
+	var status string
+	err := m.DB.QueryRow(queries.FinancialPeriodStatus, today).Scan(&status)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("no financial period covers %s, create the financial year first", today)
+	}
+	return err
+}
+
+// checkPostingPeriod checks that the financial period of a posting date is open,
+// or soft closed and the user has the period override permission
+func checkPostingPeriod(q queryRower, userID, postingDate string) error {
+	if _, err := time.Parse("2006-01-02", postingDate); err != nil {
+		return errors.New("invalid posting date")
+	}
+
+	var status string
+	err := q.QueryRow(queries.FinancialPeriodStatus, postingDate).Scan(&status)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("posting date %s does not fall within a financial period", postingDate)
+	}
+	if err != nil {
+		return err
+	}
+
+	switch status {
+	case PeriodOpen:
+		return nil
+	case PeriodSoftClosed:
+		var override bool
+		err = q.QueryRow(queries.UserPeriodOverride, userID).Scan(&override)
+		if err != nil {
+			return err
+		}
+		if override {
+			return nil
+		}
+		return fmt.Errorf("financial period of %s is soft closed", postingDate)
+	default:
+		return fmt.Errorf("financial period of %s is closed", postingDate)
+	}
+}
+
+// requirePeriodOverride returns an error unless the user has the period override permission
+func requirePeriodOverride(q queryRower, userID, action string) error {
+	var override bool
+	err := q.QueryRow(queries.UserPeriodOverride, userID).Scan(&override)
+	if err != nil {
+		return err
+	}
+
+	if !override {
+		return fmt.Errorf("%s requires the period override permission", action)
+	}
+
+	return nil
+}
+
+// createTransaction records a transaction after checking that its posting date
+// falls within a financial period the user can post to
+func createTransaction(tx *sql.Tx, userID, postingDate, remark string) (int64, error) {
+	err := checkPostingPeriod(tx, userID, postingDate)
+	if err != nil {
+		return 0, err
+	}
+
+	return insertTransaction(tx, userID, postingDate, remark)
+}
+
+// insertTransaction records a transaction without checking its financial period
+func insertTransaction(tx *sql.Tx, userID, postingDate, remark string) (int64, error) {
+	return mysequel.Insert(mysequel.Table{
+		TableName: "transaction",
+		Columns:   []string{"user_id", "datetime", "posting_date", "remark"},
+		Vals:      []interface{}{userID, time.Now().Format("2006-01-02 15:04:05"), postingDate, remark},
+		Tx:        tx,
+	})
+}
+
+// yearEndClosing computes the closing journal of a financial year from the profit
+// and loss account balances of the year. Income balances are debited and expense
+// balances credited with the net profit credited to retained earnings
+func yearEndClosing(tx *sql.Tx, fyid int, yearQuery string) (models.YearEndClosing, error) {
+	closing := models.YearEndClosing{FinancialYearID: fyid}
+	err := tx.QueryRow(yearQuery, fyid).Scan(&closing.Name, &closing.StartDate, &closing.EndDate, &closing.Status)
+	if err != nil {
+		return models.YearEndClosing{}, err
+	}
+
+	closing.RetainedEarningsAccountID, err = requirePostingAccount(tx, PostingRuleRetainedEarnings, "", "")
+	if err != nil {
+		return models.YearEndClosing{}, err
+	}
+
+	var balances []smodels.AccountBalanceForPNL
+	err = mysequel.QueryToStructs(&balances, tx, squeries.AccountSummariesForPnl, closing.StartDate, closing.EndDate)
+	if err != nil {
+		return models.YearEndClosing{}, err
+	}
+
+	total := 0.0
+	for _, balance := range balances {
+		amount := math.Round(balance.Amount*100) / 100
+		if amount == 0 {
+			continue
+		}
+
+		entry := models.YearEndClosingEntry{AccountID: balance.ID, MainAccount: balance.MainAccount, AccountName: balance.AccountName, Balance: amount}
+		if amount > 0 {
+			entry.Credit = amount
+		} else {
+			entry.Debit = -amount
+		}
+		closing.Entries = append(closing.Entries, entry)
+		total = total + amount
+	}
+	closing.NetProfit = math.Round(-total*100) / 100
+
+	return closing, nil
+}
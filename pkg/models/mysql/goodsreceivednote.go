@@ -3,6 +3,9 @@ package mysql
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
 	"net/url"
 	"strconv"
 
@@ -37,10 +40,22 @@ func (m *GoodsReceivedNoteModel) CreateGoodsReceivedNote(rparams, oparams []stri
 		return 0, err
 	}
 
+	if form.Get("order_id") != "" {
+		err = checkPurchaseOrderReceivable(tx, form.Get("order_id"))
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	currencyCode, exchangeRate, err := documentCurrency(form)
+	if err != nil {
+		return 0, err
+	}
+
 	grnid, err := mysequel.Insert(mysequel.Table{
 		TableName: "goods_received_note",
-		Columns:   []string{"user_id", "purchase_order_id", "supplier_id", "warehouse_id", "effective_date", "discount_type", "discount_amount", "price_before_discount", "total_price", "remarks"},
-		Vals:      []interface{}{form.Get("user_id"), form.Get("order_id"), form.Get("supplier_id"), form.Get("warehouse_id"), form.Get("effective_date"), form.Get("discount_type"), form.Get("discount_amount"), 0, form.Get("total_price"), form.Get("remark")},
+		Columns:   []string{"user_id", "purchase_order_id", "supplier_id", "warehouse_id", "effective_date", "discount_type", "discount_amount", "price_before_discount", "total_price", "remarks", "currency_code", "exchange_rate"},
+		Vals:      []interface{}{form.Get("user_id"), form.Get("order_id"), form.Get("supplier_id"), form.Get("warehouse_id"), form.Get("effective_date"), form.Get("discount_type"), form.Get("discount_amount"), 0, form.Get("total_price"), form.Get("remark"), currencyCode, exchangeRate},
 		Tx:        tx,
 	})
 
@@ -48,26 +63,42 @@ func (m *GoodsReceivedNoteModel) CreateGoodsReceivedNote(rparams, oparams []stri
 		return 0, err
 	}
 
+	var tolerance models.SupplierReceiptTolerance
+	if form.Get("order_id") != "" {
+		tolerance, err = supplierReceiptTolerance(tx, form.Get("supplier_id"))
+		if err != nil {
+			return 0, err
+		}
+	}
+
 	var totalPriceBeforeDiscount = 0.0
 
 	for _, entry := range gRNItem {
-
-		unitPrice, err := strconv.ParseFloat(entry.UnitPrice, 32)
+		var unitPrice, quantity float64
+		unitPrice, err = strconv.ParseFloat(entry.UnitPrice, 32)
 		if err != nil {
 			return 0, err
 		}
 
-		quantity, err := strconv.ParseFloat(entry.Quantity, 32)
+		quantity, err = strconv.ParseFloat(entry.Quantity, 32)
 		if err != nil {
 			return 0, err
 		}
 
 		totalPrice := unitPrice * quantity
 
+		variance := []interface{}{"", "", "", "", ""}
+		if form.Get("order_id") != "" {
+			variance, err = reconcileOrderItem(tx, form.Get("order_id"), grnid, entry.ItemID, quantity, unitPrice, tolerance)
+			if err != nil {
+				return 0, err
+			}
+		}
+
 		_, err = mysequel.Insert(mysequel.Table{
 			TableName: "goods_received_note_item",
-			Columns:   []string{"goods_received_note_id", "item_id", "unit_price", "qty", "total_price"},
-			Vals:      []interface{}{grnid, entry.ItemID, unitPrice, quantity, totalPrice},
+			Columns:   []string{"goods_received_note_id", "item_id", "unit_price", "qty", "total_price", "open_order_qty", "ordered_unit_price", "qty_variance", "price_variance", "variance_status"},
+			Vals:      append([]interface{}{grnid, entry.ItemID, unitPrice, quantity, totalPrice}, variance...),
 			Tx:        tx,
 		})
 
@@ -75,50 +106,6 @@ func (m *GoodsReceivedNoteModel) CreateGoodsReceivedNote(rparams, oparams []stri
 			return 0, err
 		}
 
-		if form.Get("order_id") != "" {
-			var id, orderItemQty, totalReconciled, totalCancelled float64
-
-			ids := []interface{}{entry.ItemID, form.Get("order_id")}
-
-			err := m.DB.QueryRow(queries.PurchaseOrderItemCount, ids...).Scan(&id, &orderItemQty, &totalReconciled, &totalCancelled)
-
-			if err == nil {
-				leftToreconciled := orderItemQty - (totalReconciled + totalCancelled)
-				var totalReconciledToBe float64
-				if leftToreconciled >= quantity {
-					totalReconciledToBe = totalReconciled + quantity
-				} else {
-					totalReconciledToBe = leftToreconciled
-				}
-
-				_, err := mysequel.Update(mysequel.UpdateTable{
-					Table: mysequel.Table{
-						TableName: "purchase_order_item",
-						Columns:   []string{"total_reconciled"},
-						Vals:      []interface{}{totalReconciledToBe},
-						Tx:        tx,
-					},
-					WColumns: []string{"item_id", "purchase_order_id"},
-					WVals:    []string{entry.ItemID, form.Get("order_id")},
-				})
-
-				if err != nil {
-					return 0, err
-				}
-
-				_, err = mysequel.Insert(mysequel.Table{
-					TableName: "purchase_order_item_reconciliation",
-					Columns:   []string{"purchase_order_id", "goods_received_note_id", "item_id", "qty"},
-					Vals:      []interface{}{form.Get("order_id"), grnid, entry.ItemID, quantity},
-					Tx:        tx,
-				})
-
-				if err != nil {
-					return 0, err
-				}
-			}
-		}
-
 		totalPriceBeforeDiscount = totalPriceBeforeDiscount + totalPrice
 	}
 
@@ -140,6 +127,116 @@ func (m *GoodsReceivedNoteModel) CreateGoodsReceivedNote(rparams, oparams []stri
 	return grnid, nil
 }
 
+// OpenVariances returns the goods received note lines with quantity or price
+// variances against their purchase orders that are awaiting review
+func (m *GoodsReceivedNoteModel) OpenVariances() ([]models.GRNItemVariance, error) {
+	var res []models.GRNItemVariance
+	err := mysequel.QueryToStructs(&res, m.DB, queries.GrnOpenVariances)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// ResolveVariance marks a flagged goods received note line as reviewed
+func (m *GoodsReceivedNoteModel) ResolveVariance(form url.Values) (int64, error) {
+	res, err := m.DB.Exec("UPDATE goods_received_note_item SET variance_status = 'Resolved', variance_resolved_by = ?, variance_resolved_on = NOW(), variance_resolution = ? WHERE id = ? AND variance_status = 'Open'", form.Get("user_id"), form.Get("resolution"), form.Get("grn_item_id"))
+	if err != nil {
+		return 0, err
+	}
+
+	c, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	if c == 0 {
+		return 0, errors.New("no open variance on goods received note item")
+	}
+
+	return strconv.ParseInt(form.Get("grn_item_id"), 10, 64)
+}
+
+// reconcileOrderItem reconciles a received quantity against the purchase order lines
+// of the item in line order and checks the quantity and price variances against the
+// supplier tolerance. The ordered unit price is the average of the reconciled lines.
+// It returns the open order quantity, ordered unit price, quantity variance, price variance
+// and variance status to be stored on the goods received note line
+func reconcileOrderItem(tx *sql.Tx, oid string, grnid int64, itemID string, quantity, unitPrice float64, tolerance models.SupplierReceiptTolerance) ([]interface{}, error) {
+	var lines []models.PurchaseOrderItemLine
+	err := mysequel.QueryToStructs(&lines, tx, queries.PurchaseOrderItemLines, itemID, oid)
+	if err != nil {
+		return nil, err
+	}
+
+	orderUnitPrice := unitPrice
+	if len(lines) > 0 {
+		orderUnitPrice = lines[len(lines)-1].UnitPrice
+	}
+
+	var leftToReconcile, reconciled, reconciledValue float64
+	for _, line := range lines {
+		if line.Remaining <= 0 {
+			continue
+		}
+		leftToReconcile = leftToReconcile + line.Remaining
+
+		lineQty := math.Min(quantity-reconciled, line.Remaining)
+		if lineQty <= 0 {
+			continue
+		}
+
+		_, err = tx.Exec("UPDATE purchase_order_item SET total_reconciled = total_reconciled + ? WHERE id = ?", lineQty, line.ID)
+		if err != nil {
+			return nil, err
+		}
+		reconciled = reconciled + lineQty
+		reconciledValue = reconciledValue + lineQty*line.UnitPrice
+	}
+
+	if reconciled > 0 {
+		orderUnitPrice = reconciledValue / reconciled
+	}
+
+	qtyVariance := quantity - reconciled
+	priceVariance := unitPrice - orderUnitPrice
+
+	status := ""
+	if qtyVariance > leftToReconcile*tolerance.QtyTolerance/100 || math.Abs(priceVariance) > orderUnitPrice*tolerance.PriceTolerance/100+0.005 {
+		if tolerance.Action == "Reject" {
+			return nil, fmt.Errorf("item %s exceeds the receipt tolerance of the purchase order", itemID)
+		}
+		status = "Open"
+	}
+
+	if reconciled > 0 {
+		_, err = mysequel.Insert(mysequel.Table{
+			TableName: "purchase_order_item_reconciliation",
+			Columns:   []string{"purchase_order_id", "goods_received_note_id", "item_id", "qty"},
+			Vals:      []interface{}{oid, grnid, itemID, reconciled},
+			Tx:        tx,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return []interface{}{fmt.Sprintf("%f", leftToReconcile), fmt.Sprintf("%f", orderUnitPrice), fmt.Sprintf("%f", qtyVariance), fmt.Sprintf("%f", priceVariance), status}, nil
+}
+
+// supplierReceiptTolerance returns the receipt tolerance of a supplier.
+// Suppliers without a rule have no tolerance and variances are flagged
+func supplierReceiptTolerance(tx *sql.Tx, supplierID string) (models.SupplierReceiptTolerance, error) {
+	var tolerance models.SupplierReceiptTolerance
+	err := tx.QueryRow(queries.SupplierReceiptTolerance, supplierID).Scan(&tolerance.QtyTolerance, &tolerance.PriceTolerance, &tolerance.Action)
+	if err == sql.ErrNoRows {
+		return models.SupplierReceiptTolerance{Action: "Flag"}, nil
+	}
+
+	return tolerance, err
+}
+
 func (m *GoodsReceivedNoteModel) GoodsReceivedNotesList() ([]models.GoodReceivedNoteEntry, error) {
 	var res []models.GoodReceivedNoteEntry
 	err := mysequel.QueryToStructs(&res, m.DB, queries.GoodsReceivedNoteList)
@@ -151,8 +248,8 @@ func (m *GoodsReceivedNoteModel) GoodsReceivedNotesList() ([]models.GoodReceived
 }
 
 func (m *GoodsReceivedNoteModel) GoodsReceivedNoteDetails(grnid int) (models.GoodReceivedNoteSummary, error) {
-	var id, orderDate, supplier, warehouse, priceBeforeDiscount, discountType, discountAmount, totalPrice, remarks sql.NullString
-	err := m.DB.QueryRow(queries.GoodsReceivedNoteDetails, grnid).Scan(&id, &orderDate, &supplier, &warehouse, &priceBeforeDiscount, &discountType, &discountAmount, &totalPrice, &remarks)
+	var id, orderDate, supplier, warehouse, priceBeforeDiscount, discountType, discountAmount, totalPrice, remarks, currencyCode, exchangeRate sql.NullString
+	err := m.DB.QueryRow(queries.GoodsReceivedNoteDetails, grnid).Scan(&id, &orderDate, &supplier, &warehouse, &priceBeforeDiscount, &discountType, &discountAmount, &totalPrice, &remarks, &currencyCode, &exchangeRate)
 
 	if err != nil {
 		return models.GoodReceivedNoteSummary{}, err
@@ -164,5 +261,18 @@ func (m *GoodsReceivedNoteModel) GoodsReceivedNoteDetails(grnid int) (models.Goo
 		return models.GoodReceivedNoteSummary{}, err
 	}
 
-	return models.GoodReceivedNoteSummary{GRNID: id, OrderDate: orderDate, Supplier: supplier, Warehouse: warehouse, PriceBeforeDiscount: priceBeforeDiscount, DiscountType: discountType, DiscountAmount: discountAmount, TotalPrice: totalPrice, Remarks: remarks, GRNItemDetails: grnItems}, nil
+	return models.GoodReceivedNoteSummary{GRNID: id, OrderDate: orderDate, Supplier: supplier, Warehouse: warehouse, PriceBeforeDiscount: priceBeforeDiscount, DiscountType: discountType, DiscountAmount: discountAmount, TotalPrice: totalPrice, Remarks: remarks, CurrencyCode: currencyCode, ExchangeRate: exchangeRate, GRNItemDetails: grnItems}, nil
+}
+
+// changeStockCost adds to the unit cost and landed costs of the current stock entries of an
+// item received on a goods received note. The change is recorded against each entry so that
+// stock can still be valued at the cost it had on an earlier date
+func changeStockCost(tx *sql.Tx, grnID, itemID interface{}, costPrice, landedCosts float64) error {
+	_, err := tx.Exec(queries.RecordStockCostChange, costPrice, landedCosts, costPrice+landedCosts, grnID, itemID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("UPDATE current_stock SET cost_price = cost_price + ?, landed_costs = landed_costs + ?, price = price + ? WHERE goods_received_note_id = ? AND item_id = ?", costPrice, landedCosts, costPrice+landedCosts, grnID, itemID)
+	return err
 }
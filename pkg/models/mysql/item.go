@@ -42,11 +42,20 @@ func (m *ItemModel) UpdateById(form url.Values) (int64, error) {
 		return 0, errors.New("price cannot be lower than the current price")
 	}
 
+	columns := []string{"name", "price"}
+	vals := []interface{}{form.Get("name"), form.Get("item_price")}
+	for _, param := range []string{"weight", "volume"} {
+		if form.Get(param) != "" {
+			columns = append(columns, param)
+			vals = append(vals, form.Get(param))
+		}
+	}
+
 	id, err := mysequel.Update(mysequel.UpdateTable{
 		Table: mysequel.Table{
 			TableName: "item",
-			Columns:   []string{"name", "price"},
-			Vals:      []interface{}{form.Get("name"), form.Get("item_price")},
+			Columns:   columns,
+			Vals:      vals,
 			Tx:        tx,
 		},
 		WColumns: []string{"id"},
@@ -59,6 +68,30 @@ func (m *ItemModel) UpdateById(form url.Values) (int64, error) {
 	return id, nil
 }
 
+// SetReorderLevel creates or updates the minimum and maximum stock levels of an item in a warehouse
+func (m *ItemModel) SetReorderLevel(form url.Values) (int64, error) {
+	minQty, err := strconv.Atoi(form.Get("min_qty"))
+	if err != nil {
+		return 0, err
+	}
+
+	maxQty, err := strconv.Atoi(form.Get("max_qty"))
+	if err != nil {
+		return 0, err
+	}
+
+	if minQty < 0 || maxQty < minQty {
+		return 0, errors.New("invalid reorder levels")
+	}
+
+	res, err := m.DB.Exec("INSERT INTO item_reorder_level (item_id, warehouse_id, min_qty, max_qty) VALUES (?, ?, ?, ?) ON DUPLICATE KEY UPDATE min_qty = VALUES(min_qty), max_qty = VALUES(max_qty)", form.Get("item_id"), form.Get("warehouse_id"), minQty, maxQty)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
 // Create creates an item
 func (m *ItemModel) Create(rparams, oparams []string, form url.Values) (int64, error) {
 	tx, err := m.DB.Begin()
@@ -120,6 +153,55 @@ func (m *ItemModel) Stock(id string) ([]models.CurrentStock, error) {
 	return res, nil
 }
 
+// Movements returns the stock card of an item per warehouse with running balances.
+// Movements before the start date are summarised into the opening balance. A warehouse
+// without movements of the item gets a card with a zero opening balance.
+func (m *ItemModel) Movements(id, warehouseID, startDate, endDate string) ([]models.ItemStockCard, error) {
+	w := mysequel.NewNullString(warehouseID)
+	e := mysequel.NewNullString(endDate)
+
+	var entries []models.ItemMovementEntry
+	err := mysequel.QueryToStructs(&entries, m.DB, queries.ItemMovements, id, id, id, id, id, id, id, id, w, w, e, e)
+	if err != nil {
+		return nil, err
+	}
+
+	res := []models.ItemStockCard{}
+	for _, entry := range entries {
+		if len(res) == 0 || res[len(res)-1].WarehouseID != entry.WarehouseID {
+			res = append(res, models.ItemStockCard{WarehouseID: entry.WarehouseID, Warehouse: entry.Warehouse, Movements: []models.ItemMovement{}})
+		}
+		card := &res[len(res)-1]
+
+		card.ClosingBalance = card.ClosingBalance + entry.Quantity
+		if startDate != "" && entry.Date < startDate {
+			card.OpeningBalance = card.ClosingBalance
+			continue
+		}
+
+		card.Movements = append(card.Movements, models.ItemMovement{
+			Date:         entry.Date,
+			DocumentType: entry.DocumentType,
+			DocumentID:   entry.DocumentID,
+			Quantity:     entry.Quantity,
+			Balance:      card.ClosingBalance,
+		})
+	}
+
+	if len(res) == 0 && warehouseID != "" {
+		card := models.ItemStockCard{Movements: []models.ItemMovement{}}
+		err = m.DB.QueryRow(queries.WarehouseName, warehouseID).Scan(&card.WarehouseID, &card.Warehouse)
+		if err == sql.ErrNoRows {
+			return res, nil
+		} else if err != nil {
+			return nil, err
+		}
+		res = append(res, card)
+	}
+
+	return res, nil
+}
+
 // All returns all items
 func (m *ItemModel) Details(id string) (models.ItemDetails, error) {
 	var itemDetails models.ItemDetails
@@ -6,8 +6,10 @@ import (
 	"errors"
 	"fmt"
 	"github.com/google/uuid"
+	"math"
 	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/ssrdive/basara/pkg/models"
@@ -17,18 +19,14 @@ import (
 	smodels "github.com/ssrdive/scribe/models"
 )
 
-const (
-	StockAccountID = 183
-
-	PayableAccountID = 302
-)
-
 // LandedCostModel struct holds database instance
 type LandedCostModel struct {
 	DB *sql.DB
 }
 
-// CreateLandedCost creates a Landed Cost
+// CreateLandedCost creates a Landed Cost for one or more goods received notes. The landed
+// costs are allocated across the lines of all the goods received notes together and
+// each goods received note is posted in its own transaction with its share of them
 func (m *LandedCostModel) CreateLandedCost(rparams []string, form url.Values) (int64, error) {
 	tx, err := m.DB.Begin()
 	if err != nil {
@@ -42,10 +40,12 @@ func (m *LandedCostModel) CreateLandedCost(rparams []string, form url.Values) (i
 		_ = tx.Commit()
 	}()
 
+	grnIDs := landedCostGrnIDs(form)
+
 	lcid, err := mysequel.Insert(mysequel.Table{
 		TableName: "landed_cost",
 		Columns:   []string{"user_id", "goods_received_note_id"},
-		Vals:      []interface{}{form.Get("user_id"), form.Get("grn_id")},
+		Vals:      []interface{}{form.Get("user_id"), grnIDs[0]},
 		Tx:        tx,
 	})
 
@@ -53,118 +53,311 @@ func (m *LandedCostModel) CreateLandedCost(rparams []string, form url.Values) (i
 		return 0, err
 	}
 
-	_, err = mysequel.Update(mysequel.UpdateTable{
-		Table: mysequel.Table{
-			TableName: "goods_received_note",
-			Columns:   []string{"landed_cost_id"},
-			Vals:      []interface{}{lcid},
+	for _, grnID := range grnIDs {
+		var landedCostID sql.NullInt32
+		err = tx.QueryRow("SELECT landed_cost_id FROM goods_received_note WHERE id = ? FOR UPDATE", grnID).Scan(&landedCostID)
+		if err != nil {
+			return 0, err
+		}
+
+		if landedCostID.Valid {
+			err = fmt.Errorf("goods received note %s is already costed", grnID)
+			return 0, err
+		}
+
+		_, err = mysequel.Update(mysequel.UpdateTable{
+			Table: mysequel.Table{
+				TableName: "goods_received_note",
+				Columns:   []string{"landed_cost_id"},
+				Vals:      []interface{}{lcid},
+				Tx:        tx,
+			},
+			WColumns: []string{"id"},
+			WVals:    []string{grnID},
+		})
+		if err != nil {
+			return 0, err
+		}
+
+		_, err = mysequel.Insert(mysequel.Table{
+			TableName: "landed_cost_goods_received_note",
+			Columns:   []string{"landed_cost_id", "goods_received_note_id"},
+			Vals:      []interface{}{lcid, grnID},
 			Tx:        tx,
-		},
-		WColumns: []string{"id"},
-		WVals:    []string{form.Get("grn_id")},
-	})
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
 
+	costs, err := landedCostTypeCosts(tx, form.Get("entries"))
 	if err != nil {
 		return 0, err
 	}
 
-	entries := form.Get("entries")
-	var landedCostTypes []models.LandedCostItemEntry
-	json.Unmarshal([]byte(entries), &landedCostTypes)
+	for _, cost := range costs {
+		_, err = mysequel.Insert(mysequel.Table{
+			TableName: "landed_cost_item",
+			Columns:   []string{"landed_cost_id", "landed_cost_type_id", "amount", "foreign_amount", "currency_code", "exchange_rate"},
+			Vals:      []interface{}{lcid, cost.CostTypeID, cost.Amount, cost.ForeignAmount, cost.CurrencyCode, cost.ExchangeRate},
+			Tx:        tx,
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
 
-	var totalLandedCost = 0.0
+	grnItems, err := landedCostGrnItems(tx, grnIDs)
+	if err != nil {
+		return 0, err
+	}
 
-	tid, err := mysequel.Insert(mysequel.Table{
-		TableName: "transaction",
-		Columns:   []string{"user_id", "datetime", "posting_date", "remark"},
-		Vals:      []interface{}{form.Get("user_id"), time.Now().Format("2006-01-02 15:04:05"), time.Now().Format("2006-01-02"), fmt.Sprintf("GOODS RECEIVED NOTE %s", form.Get("grn_id"))},
-		Tx:        tx,
-	})
+	allocations, err := allocateLandedCosts(grnItems, costs)
 	if err != nil {
 		return 0, err
 	}
 
-	var journalEntries []smodels.JournalEntry
-	for _, entry := range landedCostTypes {
-		costAmount, err := strconv.ParseFloat(entry.Amount, 32)
+	for _, allocation := range allocations {
+		_, err = mysequel.Insert(mysequel.Table{
+			TableName: "current_stock",
+			Columns:   []string{"entry_specifier", "warehouse_id", "item_id", "goods_received_note_id", "cost_price", "landed_costs", "qty", "float_qty", "price"},
+			Vals:      []interface{}{uuid.New(), allocation.WarehouseID, allocation.ItemID, allocation.GoodsReceivedNoteID, allocation.UnitCost, allocation.UnitLandedCost, allocation.Quantity, 0, allocation.Price},
+			Tx:        tx,
+		})
+
 		if err != nil {
 			return 0, err
 		}
-		totalLandedCost = totalLandedCost + costAmount
+	}
 
-		if costAmount == 0 {
-			continue
+	grnCosts := landedCostsByGrn(grnIDs, costs, allocations)
+	for i, grnID := range grnIDs {
+		var tid int64
+		tid, err = createTransaction(tx, form.Get("user_id"), time.Now().Format("2006-01-02"), fmt.Sprintf("GOODS RECEIVED NOTE %s", grnID))
+		if err != nil {
+			return 0, err
 		}
 
-		_, err = mysequel.Insert(mysequel.Table{
-			TableName: "landed_cost_item",
-			Columns:   []string{"landed_cost_id", "landed_cost_type_id", "amount"},
-			Vals:      []interface{}{lcid, entry.CostTypeID, entry.Amount},
-			Tx:        tx,
-		})
+		var journalEntries []smodels.JournalEntry
+		for j, cost := range costs {
+			if grnCosts[i][j] == 0 {
+				continue
+			}
+			journalEntries = append(journalEntries,
+				smodels.JournalEntry{Account: fmt.Sprintf("%d", cost.ExpenseAccountID), Debit: fmt.Sprintf("%f", grnCosts[i][j]), Credit: ""},
+				smodels.JournalEntry{Account: fmt.Sprintf("%d", cost.PayableAccountID), Debit: "", Credit: fmt.Sprintf("%f", grnCosts[i][j])},
+			)
+		}
+
+		// The amount owed to the supplier is recorded when the supplier
+		// invoice is matched against this goods received note. The stock
+		// is debited to the stock accounts of the item categories received
+		var grnCostPrice float64
+		var stockAmounts []accountAmount
+		for _, item := range grnItems {
+			if strconv.Itoa(item.GoodsReceivedNoteID) != grnID {
+				continue
+			}
+			grnCostPrice = item.TotalPrice
+
+			var stockAccountID int
+			stockAccountID, err = itemStockAccount(tx, item.ItemID)
+			if err != nil {
+				return 0, err
+			}
+			stockAmounts = addAccountAmount(stockAmounts, stockAccountID, item.ToatlCostPrice)
+		}
+
+		var payableAccountID int
+		payableAccountID, err = payableAccount(tx)
 		if err != nil {
 			return 0, err
 		}
 
-		var expenseAccountID sql.NullInt32
-		var payableAccountID sql.NullInt32
-		err = tx.QueryRow("SELECT expense_account_id, payable_account_id FROM landed_cost_type WHERE id = ?", entry.CostTypeID).Scan(&expenseAccountID, &payableAccountID)
+		journalEntries = append(journalEntries, accountAmountEntries(stockAmounts, grnCostPrice)...)
+		journalEntries = append(journalEntries, smodels.JournalEntry{Account: fmt.Sprintf("%d", payableAccountID), Debit: "", Credit: fmt.Sprintf("%f", grnCostPrice)})
+		err = scribe.IssueJournalEntries(tx, tid, journalEntries)
 		if err != nil {
 			return 0, err
 		}
+	}
 
-		if !expenseAccountID.Valid || !payableAccountID.Valid {
-			err = errors.New("expense account or payable account for landed cost item is not configured")
-			return 0, err
+	return lcid, nil
+}
+
+// PreviewLandedCost returns the allocation of landed costs to the lines of the goods
+// received notes without recording anything
+func (m *LandedCostModel) PreviewLandedCost(form url.Values) ([]models.LandedCostAllocation, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	costs, err := landedCostTypeCosts(tx, form.Get("entries"))
+	if err != nil {
+		return nil, err
+	}
+
+	grnItems, err := landedCostGrnItems(tx, landedCostGrnIDs(form))
+	if err != nil {
+		return nil, err
+	}
+
+	return allocateLandedCosts(grnItems, costs)
+}
+
+// AdjustLandedCost adds landed costs to a goods received note that is already costed.
+// The share of the units still in stock is added to the landed costs and price of
+// their current stock entries and the share of the units no longer in stock is
+// posted to cost of sales
+func (m *LandedCostModel) AdjustLandedCost(form url.Values) (int64, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
 		}
+		_ = tx.Commit()
+	}()
 
-		journalEntries = append(journalEntries,
-			smodels.JournalEntry{Account: fmt.Sprintf("%d", expenseAccountID.Int32), Debit: entry.Amount, Credit: ""},
-			smodels.JournalEntry{Account: fmt.Sprintf("%d", payableAccountID.Int32), Debit: "", Credit: entry.Amount},
-		)
+	var landedCostID sql.NullInt32
+	err = tx.QueryRow("SELECT landed_cost_id FROM goods_received_note WHERE id = ? FOR UPDATE", form.Get("grn_id")).Scan(&landedCostID)
+	if err != nil {
+		return 0, err
+	}
+
+	if !landedCostID.Valid {
+		err = errors.New("goods received note is not costed yet")
+		return 0, err
+	}
+
+	costs, err := landedCostTypeCosts(tx, form.Get("entries"))
+	if err != nil {
+		return 0, err
+	}
+
+	if len(costs) == 0 {
+		err = errors.New("landed cost adjustment has no amounts")
+		return 0, err
+	}
+
+	lcid, err := mysequel.Insert(mysequel.Table{
+		TableName: "landed_cost",
+		Columns:   []string{"user_id", "goods_received_note_id", "is_adjustment"},
+		Vals:      []interface{}{form.Get("user_id"), form.Get("grn_id"), 1},
+		Tx:        tx,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = mysequel.Insert(mysequel.Table{
+		TableName: "landed_cost_goods_received_note",
+		Columns:   []string{"landed_cost_id", "goods_received_note_id"},
+		Vals:      []interface{}{lcid, form.Get("grn_id")},
+		Tx:        tx,
+	})
+	if err != nil {
+		return 0, err
 	}
 
 	var grnItems []models.GRNItemDetailsWithTotal
-	err = mysequel.QueryToStructs(&grnItems, m.DB, queries.GrnItemDetailsWithOrderTotal, form.Get("grn_id"))
+	err = mysequel.QueryToStructs(&grnItems, tx, queries.GrnItemDetailsWithOrderTotal, form.Get("grn_id"))
+	if err != nil {
+		return 0, err
+	}
+
+	allocations, err := allocateLandedCosts(grnItems, costs)
 	if err != nil {
 		return 0, err
 	}
 
-	for _, entry := range grnItems {
+	var remainingStock []models.GRNItemRemainingStock
+	err = mysequel.QueryToStructs(&remainingStock, tx, queries.GrnItemRemainingStock, form.Get("grn_id"))
+	if err != nil {
+		return 0, err
+	}
 
-		landedCost := (entry.ToatlCostPrice * totalLandedCost) / (entry.TotalPrice * entry.Quantity)
-		unitCost := (entry.ToatlCostPrice / entry.Quantity)
+	remainingQty := make(map[int]float64)
+	for _, stock := range remainingStock {
+		remainingQty[stock.ItemID] = stock.Qty
+	}
 
-		_, err = mysequel.Insert(mysequel.Table{
-			TableName: "current_stock",
-			Columns:   []string{"entry_specifier", "warehouse_id", "item_id", "goods_received_note_id", "cost_price", "landed_costs", "qty", "float_qty", "price"},
-			Vals:      []interface{}{uuid.New(), entry.WarehouseId, entry.ItemID, form.Get("grn_id"), unitCost, landedCost, entry.Quantity, 0, (unitCost + landedCost)},
-			Tx:        tx,
-		})
+	receivedQty := make(map[int]float64)
+	itemLandedCost := make(map[int]float64)
+	for _, allocation := range allocations {
+		receivedQty[allocation.ItemID] = receivedQty[allocation.ItemID] + allocation.Quantity
+		itemLandedCost[allocation.ItemID] = itemLandedCost[allocation.ItemID] + allocation.LandedCost
+	}
+
+	for itemID, landedCost := range itemLandedCost {
+		unitLandedCost := landedCost / receivedQty[itemID]
+		err = changeStockCost(tx, form.Get("grn_id"), itemID, 0, unitLandedCost)
+		if err != nil {
+			return 0, err
+		}
+	}
 
+	// Units that are no longer in stock carry their share of each landed
+	// cost type straight to the cost of sales account of their item category
+	warehouseID := strconv.Itoa(grnItems[0].WarehouseId)
+	soldAmounts := make([]float64, len(costs))
+	var costOfSalesAmounts []accountAmount
+	for _, allocation := range allocations {
+		soldRatio := math.Max(receivedQty[allocation.ItemID]-remainingQty[allocation.ItemID], 0) / receivedQty[allocation.ItemID]
+		if soldRatio == 0 {
+			continue
+		}
+
+		var itemCategoryID string
+		err = tx.QueryRow(queries.ItemCategoryOfItem, allocation.ItemID).Scan(&itemCategoryID)
+		if err != nil {
+			return 0, err
+		}
+
+		var costOfSalesAccountID int
+		costOfSalesAccountID, err = requirePostingAccount(tx, PostingRuleCostOfSales, itemCategoryID, warehouseID)
 		if err != nil {
 			return 0, err
 		}
+
+		for i, line := range allocation.Breakdown {
+			soldAmounts[i] = soldAmounts[i] + (line.Amount * soldRatio)
+			costOfSalesAmounts = addAccountAmount(costOfSalesAmounts, costOfSalesAccountID, line.Amount*soldRatio)
+		}
 	}
 
-	var businessPartnerID int32
-	err = tx.QueryRow("SELECT supplier_id FROM goods_received_note WHERE id = ?", form.Get("grn_id")).Scan(&businessPartnerID)
+	tid, err := createTransaction(tx, form.Get("user_id"), time.Now().Format("2006-01-02"), fmt.Sprintf("LANDED COST ADJUSTMENT %d [GOODS RECEIVED NOTE %s]", lcid, form.Get("grn_id")))
 	if err != nil {
 		return 0, err
 	}
 
-	grnCostPrice := grnItems[0].TotalPrice
-	_, err = mysequel.Insert(mysequel.Table{
-		TableName: "business_partner_financial",
-		Columns:   []string{"business_partner_id", "type", "amount", "transaction_id"},
-		Vals:      []interface{}{businessPartnerID, "CR", grnCostPrice, tid},
-		Tx:        tx,
-	})
+	var journalEntries []smodels.JournalEntry
+	var totalSold float64
+	for i, cost := range costs {
+		_, err = mysequel.Insert(mysequel.Table{
+			TableName: "landed_cost_item",
+			Columns:   []string{"landed_cost_id", "landed_cost_type_id", "amount", "foreign_amount", "currency_code", "exchange_rate"},
+			Vals:      []interface{}{lcid, cost.CostTypeID, cost.Amount, cost.ForeignAmount, cost.CurrencyCode, cost.ExchangeRate},
+			Tx:        tx,
+		})
+		if err != nil {
+			return 0, err
+		}
+
+		soldAmount := math.Round(soldAmounts[i]*100) / 100
+		totalSold = totalSold + soldAmount
+		if cost.Amount-soldAmount > 0 {
+			journalEntries = append(journalEntries, smodels.JournalEntry{Account: fmt.Sprintf("%d", cost.ExpenseAccountID), Debit: fmt.Sprintf("%f", cost.Amount-soldAmount), Credit: ""})
+		}
+		journalEntries = append(journalEntries, smodels.JournalEntry{Account: fmt.Sprintf("%d", cost.PayableAccountID), Debit: "", Credit: fmt.Sprintf("%f", cost.Amount)})
+	}
+	journalEntries = append(journalEntries, accountAmountEntries(costOfSalesAmounts, totalSold)...)
 
-	journalEntries = append(journalEntries,
-		smodels.JournalEntry{Account: fmt.Sprintf("%d", StockAccountID), Debit: fmt.Sprintf("%f", grnCostPrice), Credit: ""},
-		smodels.JournalEntry{Account: fmt.Sprintf("%d", PayableAccountID), Debit: "", Credit: fmt.Sprintf("%f", grnCostPrice)},
-	)
 	err = scribe.IssueJournalEntries(tx, tid, journalEntries)
 	if err != nil {
 		return 0, err
@@ -172,3 +365,210 @@ func (m *LandedCostModel) CreateLandedCost(rparams []string, form url.Values) (i
 
 	return lcid, nil
 }
+
+// landedCostAllocationBases holds the bases landed costs can be allocated on
+var landedCostAllocationBases = []string{"Value", "Quantity", "Weight", "Volume"}
+
+// SetAllocationBasis sets the basis the landed costs of a landed cost type are
+// allocated to goods received note lines on
+func (m *LandedCostModel) SetAllocationBasis(form url.Values) (int64, error) {
+	basis := form.Get("allocation_basis")
+	valid := false
+	for _, b := range landedCostAllocationBases {
+		if b == basis {
+			valid = true
+		}
+	}
+	if !valid {
+		return 0, fmt.Errorf("unknown allocation basis %s", basis)
+	}
+
+	var id int64
+	err := m.DB.QueryRow("SELECT id FROM landed_cost_type WHERE id = ?", form.Get("landed_cost_type_id")).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = m.DB.Exec("UPDATE landed_cost_type SET allocation_basis = ? WHERE id = ?", basis, id)
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// landedCostGrnIDs returns the goods received notes of a landed cost from the comma
+// separated grn_ids parameter, falling back to the single grn_id parameter
+func landedCostGrnIDs(form url.Values) []string {
+	if form.Get("grn_ids") == "" {
+		return []string{form.Get("grn_id")}
+	}
+
+	var grnIDs []string
+	for _, grnID := range strings.Split(form.Get("grn_ids"), ",") {
+		if grnID = strings.TrimSpace(grnID); grnID != "" {
+			grnIDs = append(grnIDs, grnID)
+		}
+	}
+
+	return grnIDs
+}
+
+// landedCostGrnItems returns the lines of all the goods received notes of a landed cost
+func landedCostGrnItems(tx *sql.Tx, grnIDs []string) ([]models.GRNItemDetailsWithTotal, error) {
+	var grnItems []models.GRNItemDetailsWithTotal
+	for _, grnID := range grnIDs {
+		var items []models.GRNItemDetailsWithTotal
+		err := mysequel.QueryToStructs(&items, tx, queries.GrnItemDetailsWithOrderTotal, grnID)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(items) == 0 {
+			return nil, fmt.Errorf("goods received note %s has no items", grnID)
+		}
+
+		grnItems = append(grnItems, items...)
+	}
+
+	return grnItems, nil
+}
+
+// landedCostsByGrn splits every landed cost amount between the goods received notes
+// according to the allocation. Shares are rounded to cents and the last goods
+// received note takes the rounding difference
+func landedCostsByGrn(grnIDs []string, costs []models.LandedCostAllocationCost, allocations []models.LandedCostAllocation) [][]float64 {
+	grnCosts := make([][]float64, len(grnIDs))
+	for i, grnID := range grnIDs {
+		grnCosts[i] = make([]float64, len(costs))
+		for _, allocation := range allocations {
+			if strconv.Itoa(allocation.GoodsReceivedNoteID) != grnID {
+				continue
+			}
+			for j, line := range allocation.Breakdown {
+				grnCosts[i][j] = grnCosts[i][j] + line.Amount
+			}
+		}
+	}
+
+	for j, cost := range costs {
+		allocated := 0.0
+		for i := range grnIDs {
+			if i == len(grnIDs)-1 {
+				grnCosts[i][j] = math.Round((cost.Amount-allocated)*100) / 100
+				break
+			}
+			grnCosts[i][j] = math.Round(grnCosts[i][j]*100) / 100
+			allocated = allocated + grnCosts[i][j]
+		}
+	}
+
+	return grnCosts
+}
+
+// landedCostTypeCosts parses landed cost entries, converts their amounts to the base
+// currency and looks up the accounts and allocation basis of their types.
+// Entries without an amount are left out
+func landedCostTypeCosts(tx *sql.Tx, entries string) ([]models.LandedCostAllocationCost, error) {
+	var landedCostTypes []models.LandedCostItemEntry
+	err := json.Unmarshal([]byte(entries), &landedCostTypes)
+	if err != nil {
+		return nil, err
+	}
+
+	var costs []models.LandedCostAllocationCost
+	for _, entry := range landedCostTypes {
+		costAmount, err := strconv.ParseFloat(entry.Amount, 64)
+		if err != nil {
+			return nil, err
+		}
+
+		if costAmount == 0 {
+			continue
+		}
+
+		currencyCode, exchangeRate, err := currencyRate(entry.CurrencyCode, entry.ExchangeRate)
+		if err != nil {
+			return nil, err
+		}
+
+		var expenseAccountID, payableAccountID sql.NullInt32
+		var allocationBasis string
+		err = tx.QueryRow(queries.LandedCostTypeAccounts, entry.CostTypeID).Scan(&expenseAccountID, &payableAccountID, &allocationBasis)
+		if err != nil {
+			return nil, err
+		}
+
+		if !expenseAccountID.Valid || !payableAccountID.Valid {
+			return nil, errors.New("expense account or payable account for landed cost item is not configured")
+		}
+
+		costs = append(costs, models.LandedCostAllocationCost{
+			CostTypeID:       entry.CostTypeID,
+			AllocationBasis:  allocationBasis,
+			Amount:           math.Round(costAmount*exchangeRate*100) / 100,
+			ForeignAmount:    costAmount,
+			CurrencyCode:     currencyCode,
+			ExchangeRate:     exchangeRate,
+			ExpenseAccountID: int(expenseAccountID.Int32),
+			PayableAccountID: int(payableAccountID.Int32),
+		})
+	}
+
+	return costs, nil
+}
+
+// allocateLandedCosts spreads each landed cost over the goods received note lines
+// in proportion to the allocation basis of its type and returns the unit cost,
+// unit landed cost and price of every line with the breakdown per landed cost type
+func allocateLandedCosts(grnItems []models.GRNItemDetailsWithTotal, costs []models.LandedCostAllocationCost) ([]models.LandedCostAllocation, error) {
+	allocations := make([]models.LandedCostAllocation, len(grnItems))
+	for i, item := range grnItems {
+		allocations[i] = models.LandedCostAllocation{
+			GoodsReceivedNoteID: item.GoodsReceivedNoteID,
+			GRNItemID:           item.GRNID,
+			ItemID:              item.ItemID,
+			WarehouseID:         item.WarehouseId,
+			Quantity:            item.Quantity,
+			TotalCostPrice:      item.ToatlCostPrice,
+			UnitCost:            item.ToatlCostPrice / item.Quantity,
+		}
+	}
+
+	for _, cost := range costs {
+		bases := make([]float64, len(grnItems))
+		totalBasis := 0.0
+		for i, item := range grnItems {
+			switch cost.AllocationBasis {
+			case "Value":
+				bases[i] = item.ToatlCostPrice
+			case "Quantity":
+				bases[i] = item.Quantity
+			case "Weight":
+				bases[i] = item.Quantity * item.Weight
+			case "Volume":
+				bases[i] = item.Quantity * item.Volume
+			default:
+				return nil, fmt.Errorf("unknown allocation basis %s for landed cost type %s", cost.AllocationBasis, cost.CostTypeID)
+			}
+			totalBasis = totalBasis + bases[i]
+		}
+
+		if totalBasis == 0 {
+			return nil, fmt.Errorf("goods received note items have no %s to allocate landed cost type %s", strings.ToLower(cost.AllocationBasis), cost.CostTypeID)
+		}
+
+		for i := range allocations {
+			amount := cost.Amount * bases[i] / totalBasis
+			allocations[i].LandedCost = allocations[i].LandedCost + amount
+			allocations[i].Breakdown = append(allocations[i].Breakdown, models.LandedCostAllocationLine{CostTypeID: cost.CostTypeID, AllocationBasis: cost.AllocationBasis, Amount: amount})
+		}
+	}
+
+	for i := range allocations {
+		allocations[i].UnitLandedCost = allocations[i].LandedCost / allocations[i].Quantity
+		allocations[i].Price = allocations[i].UnitCost + allocations[i].UnitLandedCost
+	}
+
+	return allocations, nil
+}
@@ -0,0 +1,302 @@
+package mysql
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"strings"
+
+	"github.com/ssrdive/basara/pkg/models"
+	"github.com/ssrdive/basara/pkg/sql/queries"
+	"github.com/ssrdive/mysequel"
+	smodels "github.com/ssrdive/scribe/models"
+)
+
+// Posting rules map the accounts a posting is made to. A rule may be specific to an
+// item category, a warehouse or both, falling back to the rule without either.
+// Stock rules cannot be specific to a warehouse as inventory transfers move stock
+// between warehouses without posting, and payable rules cannot be specific to either
+// as supplier payments are made against neither
+const (
+	PostingRuleStock            = "Stock"
+	PostingRulePayable          = "Payable"
+	PostingRuleSales            = "Sales"
+	PostingRuleCostOfSales      = "CostOfSales"
+	PostingRuleExchangeGainLoss = "ExchangeGainLoss"
+	PostingRuleRetainedEarnings = "RetainedEarnings"
+)
+
+// requiredPostingRules must have a default rule for the application to start.
+// Exchange gains and losses are only required once foreign currencies are used
+// and retained earnings only at the first year end closing
+var requiredPostingRules = []string{PostingRuleStock, PostingRulePayable, PostingRuleSales, PostingRuleCostOfSales}
+
+// queryRower is satisfied by both *sql.DB and *sql.Tx
+type queryRower interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// PostingRuleModel struct holds database instance
+type PostingRuleModel struct {
+	DB *sql.DB
+}
+
+// Set creates or replaces the posting rule for a rule name, item category and warehouse
+func (m *PostingRuleModel) Set(form url.Values) (int64, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+			return
+		}
+		_ = tx.Commit()
+	}()
+
+	rule := form.Get("rule")
+	if !validPostingRule(rule) {
+		err = fmt.Errorf("unknown posting rule %s", rule)
+		return 0, err
+	}
+
+	if rule == PostingRuleStock && form.Get("warehouse_id") != "" {
+		err = errors.New("stock posting rules cannot be specific to a warehouse")
+		return 0, err
+	}
+
+	if rule == PostingRulePayable && (form.Get("item_category_id") != "" || form.Get("warehouse_id") != "") {
+		err = errors.New("payable posting rules cannot be specific to an item category or warehouse")
+		return 0, err
+	}
+
+	itemCategoryID := mysequel.NewNullString(form.Get("item_category_id"))
+	warehouseID := mysequel.NewNullString(form.Get("warehouse_id"))
+	_, err = tx.Exec("DELETE FROM posting_rule WHERE rule = ? AND item_category_id <=> ? AND warehouse_id <=> ?", rule, itemCategoryID, warehouseID)
+	if err != nil {
+		return 0, err
+	}
+
+	return mysequel.Insert(mysequel.Table{
+		TableName: "posting_rule",
+		Columns:   []string{"user_id", "rule", "item_category_id", "warehouse_id", "account_id"},
+		Vals:      []interface{}{form.Get("user_id"), rule, form.Get("item_category_id"), form.Get("warehouse_id"), form.Get("account_id")},
+		Tx:        tx,
+	})
+}
+
+// List returns the posting rules
+func (m *PostingRuleModel) List() ([]models.PostingRule, error) {
+	var res []models.PostingRule
+	err := mysequel.QueryToStructs(&res, m.DB, queries.PostingRuleList)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// Validate checks that every required posting rule has a default, that every
+// posting rule maps to an existing account and that no stock or payable rule is
+// more specific than allowed
+func (m *PostingRuleModel) Validate() error {
+	var missing []string
+	for _, rule := range requiredPostingRules {
+		_, err := postingAccount(m.DB, rule, "", "")
+		if err == sql.ErrNoRows {
+			missing = append(missing, rule)
+		} else if err != nil {
+			return err
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("posting rules missing a default account: %s", strings.Join(missing, ", "))
+	}
+
+	var invalid int
+	err := m.DB.QueryRow(queries.PostingRulesWithoutAccount).Scan(&invalid)
+	if err != nil {
+		return err
+	}
+
+	if invalid > 0 {
+		return fmt.Errorf("%d posting rules map to accounts that do not exist", invalid)
+	}
+
+	err = m.DB.QueryRow(queries.PostingRulesTooSpecific, PostingRuleStock, PostingRulePayable).Scan(&invalid)
+	if err != nil {
+		return err
+	}
+
+	if invalid > 0 {
+		return fmt.Errorf("%d stock or payable posting rules are specific to a warehouse or item category they cannot be", invalid)
+	}
+
+	return nil
+}
+
+// postingAccount resolves the account of a posting rule preferring a rule for the
+// item category and warehouse, then the item category, then the warehouse and
+// then the default. An empty item category or warehouse matches only rules without one
+func postingAccount(q queryRower, rule, itemCategoryID, warehouseID string) (int, error) {
+	var accountID int
+	err := q.QueryRow(queries.PostingRuleAccount, rule, mysequel.NewNullString(itemCategoryID), mysequel.NewNullString(warehouseID)).Scan(&accountID)
+	return accountID, err
+}
+
+// requirePostingAccount resolves the account of a posting rule and describes
+// the missing rule when there is none
+func requirePostingAccount(q queryRower, rule, itemCategoryID, warehouseID string) (int, error) {
+	accountID, err := postingAccount(q, rule, itemCategoryID, warehouseID)
+	if err == sql.ErrNoRows {
+		return 0, fmt.Errorf("no %s posting rule for item category %q and warehouse %q", rule, itemCategoryID, warehouseID)
+	}
+
+	return accountID, err
+}
+
+// stockAccount resolves the stock account of an item category
+func stockAccount(q queryRower, itemCategoryID string) (int, error) {
+	return requirePostingAccount(q, PostingRuleStock, itemCategoryID, "")
+}
+
+// itemStockAccount resolves the stock account of the item category of an item
+func itemStockAccount(q queryRower, itemID interface{}) (int, error) {
+	var itemCategoryID string
+	err := q.QueryRow(queries.ItemCategoryOfItem, itemID).Scan(&itemCategoryID)
+	if err != nil {
+		return 0, err
+	}
+
+	return stockAccount(q, itemCategoryID)
+}
+
+// payableAccount resolves the payable account
+func payableAccount(q queryRower) (int, error) {
+	return requirePostingAccount(q, PostingRulePayable, "", "")
+}
+
+// salesItem holds the price and cost of an item sold
+type salesItem struct {
+	ItemID int
+	Price  float64
+	Cost   float64
+}
+
+// salesPosting holds the sales and cost of items sold to be posted to the
+// accounts of their posting rules
+type salesPosting struct {
+	SalesAccountID       int
+	CostOfSalesAccountID int
+	StockAccountID       int
+	Price                float64
+	Cost                 float64
+}
+
+// salesPostings groups the price and cost of items sold from a warehouse by the
+// accounts of their item categories. Prices are reduced by the discount percentage
+// with any rounding difference on the last group so the total matches priceAfterDiscount
+func salesPostings(q queryRower, warehouseID string, items []salesItem, priceAfterDiscount, discount float64) ([]salesPosting, error) {
+	var postings []salesPosting
+	for _, item := range items {
+		var itemCategoryID string
+		err := q.QueryRow(queries.ItemCategoryOfItem, item.ItemID).Scan(&itemCategoryID)
+		if err != nil {
+			return nil, err
+		}
+
+		var p salesPosting
+		p.SalesAccountID, err = requirePostingAccount(q, PostingRuleSales, itemCategoryID, warehouseID)
+		if err != nil {
+			return nil, err
+		}
+		p.CostOfSalesAccountID, err = requirePostingAccount(q, PostingRuleCostOfSales, itemCategoryID, warehouseID)
+		if err != nil {
+			return nil, err
+		}
+		p.StockAccountID, err = stockAccount(q, itemCategoryID)
+		if err != nil {
+			return nil, err
+		}
+
+		found := false
+		for i := range postings {
+			if postings[i].SalesAccountID == p.SalesAccountID && postings[i].CostOfSalesAccountID == p.CostOfSalesAccountID && postings[i].StockAccountID == p.StockAccountID {
+				postings[i].Price = postings[i].Price + item.Price
+				postings[i].Cost = postings[i].Cost + item.Cost
+				found = true
+			}
+		}
+		if !found {
+			p.Price = item.Price
+			p.Cost = item.Cost
+			postings = append(postings, p)
+		}
+	}
+
+	remaining := priceAfterDiscount
+	for i := range postings {
+		postings[i].Cost = math.Round(postings[i].Cost*100) / 100
+		if i == len(postings)-1 {
+			postings[i].Price = math.Round(remaining*100) / 100
+			continue
+		}
+		postings[i].Price = math.Round((postings[i].Price*(100-discount)/100)*100) / 100
+		remaining = remaining - postings[i].Price
+	}
+
+	return postings, nil
+}
+
+// validPostingRule reports whether rule is a known posting rule name
+func validPostingRule(rule string) bool {
+	for _, r := range []string{PostingRuleStock, PostingRulePayable, PostingRuleSales, PostingRuleCostOfSales, PostingRuleExchangeGainLoss, PostingRuleRetainedEarnings} {
+		if r == rule {
+			return true
+		}
+	}
+	return false
+}
+
+// accountAmount holds an amount to be posted to an account, debits being positive
+type accountAmount struct {
+	AccountID int
+	Amount    float64
+}
+
+// addAccountAmount adds an amount to an account, appending the account when it has no amount yet
+func addAccountAmount(amounts []accountAmount, accountID int, amount float64) []accountAmount {
+	for i := range amounts {
+		if amounts[i].AccountID == accountID {
+			amounts[i].Amount = amounts[i].Amount + amount
+			return amounts
+		}
+	}
+	return append(amounts, accountAmount{AccountID: accountID, Amount: amount})
+}
+
+// accountAmountEntries rounds the amounts to cents with the rounding difference on the
+// last account so that they add up to total and returns them as journal entries
+func accountAmountEntries(amounts []accountAmount, total float64) []smodels.JournalEntry {
+	var journalEntries []smodels.JournalEntry
+	remaining := total
+	for i, a := range amounts {
+		amount := math.Round(a.Amount*100) / 100
+		if i == len(amounts)-1 {
+			amount = math.Round(remaining*100) / 100
+		}
+		remaining = remaining - amount
+
+		if amount > 0 {
+			journalEntries = append(journalEntries, smodels.JournalEntry{Account: fmt.Sprintf("%d", a.AccountID), Debit: fmt.Sprintf("%f", amount), Credit: ""})
+		} else if amount < 0 {
+			journalEntries = append(journalEntries, smodels.JournalEntry{Account: fmt.Sprintf("%d", a.AccountID), Debit: "", Credit: fmt.Sprintf("%f", -amount)})
+		}
+	}
+
+	return journalEntries
+}
@@ -3,6 +3,9 @@ package mysql
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
 	"net/url"
 	"strconv"
 
@@ -11,9 +14,11 @@ import (
 	"github.com/ssrdive/mysequel"
 )
 
-// PurchaseOrderModel struct holds database instance
+// PurchaseOrderModel struct holds database instance and the order value
+// up to which any user can approve a purchase order
 type PurchaseOrderModel struct {
-	DB *sql.DB
+	DB                *sql.DB
+	ApprovalThreshold float64
 }
 
 // CreatePurchaseOrder creats an purchase order
@@ -34,10 +39,15 @@ func (m *PurchaseOrderModel) CreatePurchaseOrder(rparams, oparams []string, form
 	var orderItem []models.OrderItemEntry
 	json.Unmarshal([]byte(entities), &orderItem)
 
+	currencyCode, exchangeRate, err := documentCurrency(form)
+	if err != nil {
+		return 0, err
+	}
+
 	oid, err := mysequel.Insert(mysequel.Table{
 		TableName: "purchase_order",
-		Columns:   []string{"user_id", "supplier_id", "warehouse_id", "discount_type", "discount_amount", "price_before_discount", "total_price", "remarks"},
-		Vals:      []interface{}{form.Get("user_id"), form.Get("supplier_id"), form.Get("warehouse_id"), form.Get("discount_type"), form.Get("discount_amount"), 0, form.Get("total_price"), form.Get("remark")},
+		Columns:   []string{"user_id", "supplier_id", "warehouse_id", "discount_type", "discount_amount", "price_before_discount", "total_price", "remarks", "approval_status", "currency_code", "exchange_rate"},
+		Vals:      []interface{}{form.Get("user_id"), form.Get("supplier_id"), form.Get("warehouse_id"), form.Get("discount_type"), form.Get("discount_amount"), 0, form.Get("total_price"), form.Get("remark"), "Draft", currencyCode, exchangeRate},
 		Tx:        tx,
 	})
 
@@ -98,6 +108,271 @@ func (m *PurchaseOrderModel) CreatePurchaseOrder(rparams, oparams []string, form
 	return oid, nil
 }
 
+// CreateFromReorderSuggestions creates a purchase order for the suggested quantities of a supplier
+func (m *PurchaseOrderModel) CreateFromReorderSuggestions(userID, supplierID, warehouseID string, suggestions []models.ReorderSupplierSuggestions) (int64, error) {
+	var orderItems []models.OrderItemEntry
+	totalPrice := 0.0
+	for _, group := range suggestions {
+		if strconv.Itoa(group.SupplierID) != supplierID {
+			continue
+		}
+		for _, item := range group.Items {
+			orderItems = append(orderItems, models.OrderItemEntry{
+				ItemID:    strconv.Itoa(item.ID),
+				Quantity:  strconv.Itoa(item.SuggestedQty),
+				UnitPrice: fmt.Sprintf("%f", item.UnitPrice),
+			})
+			totalPrice = totalPrice + (item.UnitPrice * float64(item.SuggestedQty))
+		}
+	}
+
+	if len(orderItems) == 0 {
+		return 0, errors.New("no reorder suggestions for the supplier")
+	}
+
+	entries, err := json.Marshal(orderItems)
+	if err != nil {
+		return 0, err
+	}
+
+	form := url.Values{}
+	form.Set("user_id", userID)
+	form.Set("supplier_id", supplierID)
+	form.Set("warehouse_id", warehouseID)
+	form.Set("entries", string(entries))
+	form.Set("total_price", fmt.Sprintf("%f", totalPrice))
+	form.Set("remark", "Generated from reorder suggestions")
+
+	return m.CreatePurchaseOrder([]string{"supplier_id", "warehouse_id", "entries"}, []string{"remark"}, form)
+}
+
+// CancelItem cancels the remaining quantity of an item on a purchase order, or part
+// of it when a quantity is given. The quantity is cancelled from the lines of the
+// item in line order
+func (m *PurchaseOrderModel) CancelItem(form url.Values) (int64, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		_ = tx.Commit()
+	}()
+
+	err = checkPurchaseOrderOpen(tx, form.Get("purchase_order_id"))
+	if err != nil {
+		return 0, err
+	}
+
+	var lines []models.PurchaseOrderItemLine
+	err = mysequel.QueryToStructs(&lines, tx, queries.PurchaseOrderItemLines, form.Get("item_id"), form.Get("purchase_order_id"))
+	if err != nil {
+		return 0, err
+	}
+
+	if len(lines) == 0 {
+		err = sql.ErrNoRows
+		return 0, err
+	}
+
+	var remaining float64
+	for _, line := range lines {
+		if line.Remaining > 0 {
+			remaining = remaining + line.Remaining
+		}
+	}
+
+	qty := remaining
+	if form.Get("qty") != "" {
+		qty, err = strconv.ParseFloat(form.Get("qty"), 32)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if qty <= 0 || qty > remaining {
+		err = errors.New("invalid cancellation quantity")
+		return 0, err
+	}
+
+	var cid int64
+	for _, line := range lines {
+		lineQty := math.Min(qty, line.Remaining)
+		if lineQty <= 0 {
+			continue
+		}
+
+		cid, err = cancelPurchaseOrderItem(tx, form.Get("user_id"), form.Get("purchase_order_id"), int64(line.ID), lineQty, form.Get("reason"))
+		if err != nil {
+			return 0, err
+		}
+		qty = qty - lineQty
+	}
+
+	return cid, nil
+}
+
+// ClosePurchaseOrder cancels every remaining quantity of a purchase order and marks it as closed
+func (m *PurchaseOrderModel) ClosePurchaseOrder(form url.Values) (int64, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		_ = tx.Commit()
+	}()
+
+	oid := form.Get("purchase_order_id")
+
+	err = checkPurchaseOrderOpen(tx, oid)
+	if err != nil {
+		return 0, err
+	}
+
+	var items []models.PurchaseOrderItemRemaining
+	err = mysequel.QueryToStructs(&items, tx, queries.PurchaseOrderItemsRemaining, oid)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, item := range items {
+		_, err = cancelPurchaseOrderItem(tx, form.Get("user_id"), oid, int64(item.ID), item.Remaining, form.Get("reason"))
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	_, err = tx.Exec("UPDATE purchase_order SET closed_by = ?, closed_on = NOW(), close_reason = ? WHERE id = ?", form.Get("user_id"), form.Get("reason"), oid)
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.ParseInt(oid, 10, 64)
+}
+
+// Approve approves or rejects a draft purchase order. Orders above the approval
+// threshold need an approver whose limit covers the order value
+func (m *PurchaseOrderModel) Approve(form url.Values) (int64, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		_ = tx.Commit()
+	}()
+
+	status := form.Get("status")
+	if status != "Approved" && status != "Rejected" {
+		err = errors.New("invalid approval status")
+		return 0, err
+	}
+
+	oid := form.Get("purchase_order_id")
+
+	var closedOn sql.NullString
+	var approvalStatus string
+	var totalPrice float64
+	err = tx.QueryRow(queries.PurchaseOrderState, oid).Scan(&closedOn, &approvalStatus, &totalPrice)
+	if err != nil {
+		return 0, err
+	}
+
+	if approvalStatus != "Draft" {
+		err = errors.New("purchase order is not a draft")
+		return 0, err
+	}
+
+	var createdBy string
+	err = tx.QueryRow("SELECT user_id FROM purchase_order WHERE id = ?", oid).Scan(&createdBy)
+	if err != nil {
+		return 0, err
+	}
+
+	if createdBy == form.Get("user_id") {
+		err = errors.New("purchase order cannot be approved by the user who created it")
+		return 0, err
+	}
+
+	if totalPrice > m.ApprovalThreshold {
+		var limit float64
+		err = tx.QueryRow(queries.UserPurchaseOrderApprovalLimit, form.Get("user_id")).Scan(&limit)
+		if err == sql.ErrNoRows || (err == nil && limit < totalPrice) {
+			err = errors.New("insufficient approval authority")
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	_, err = tx.Exec("UPDATE purchase_order SET approval_status = ?, approved_by = ?, approved_on = NOW() WHERE id = ?", status, form.Get("user_id"), oid)
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.ParseInt(oid, 10, 64)
+}
+
+func checkPurchaseOrderOpen(tx *sql.Tx, oid string) error {
+	var closedOn sql.NullString
+	var approvalStatus string
+	var totalPrice float64
+	err := tx.QueryRow(queries.PurchaseOrderState, oid).Scan(&closedOn, &approvalStatus, &totalPrice)
+	if err != nil {
+		return err
+	}
+
+	if closedOn.Valid {
+		return errors.New("purchase order is closed")
+	}
+
+	return nil
+}
+
+func checkPurchaseOrderReceivable(tx *sql.Tx, oid string) error {
+	var closedOn sql.NullString
+	var approvalStatus string
+	var totalPrice float64
+	err := tx.QueryRow(queries.PurchaseOrderState, oid).Scan(&closedOn, &approvalStatus, &totalPrice)
+	if err != nil {
+		return err
+	}
+
+	if closedOn.Valid {
+		return errors.New("purchase order is closed")
+	}
+
+	if approvalStatus != "Approved" {
+		return errors.New("purchase order is not approved")
+	}
+
+	return nil
+}
+
+func cancelPurchaseOrderItem(tx *sql.Tx, userID, oid string, oiid int64, qty float64, reason string) (int64, error) {
+	_, err := tx.Exec("UPDATE purchase_order_item SET total_cancelled = total_cancelled + ? WHERE id = ?", qty, oiid)
+	if err != nil {
+		return 0, err
+	}
+
+	return mysequel.Insert(mysequel.Table{
+		TableName: "purchase_order_cancellation",
+		Columns:   []string{"purchase_order_id", "purchase_order_item_id", "user_id", "qty", "reason"},
+		Vals:      []interface{}{oid, oiid, userID, qty, reason},
+		Tx:        tx,
+	})
+}
+
 func (m *PurchaseOrderModel) PurchaseOrderList() ([]models.PurchaseOrderEntry, error) {
 	var res []models.PurchaseOrderEntry
 	err := mysequel.QueryToStructs(&res, m.DB, queries.PurchaseOrderList)
@@ -109,8 +384,8 @@ func (m *PurchaseOrderModel) PurchaseOrderList() ([]models.PurchaseOrderEntry, e
 }
 
 func (m *PurchaseOrderModel) PurchaseOrderDetails(oid int) (models.PurchaseOrderSummary, error) {
-	var id, orderDate, supplier, warehouse, priceBeforeDiscount, discountType, discountAmount, totalPrice, remarks sql.NullString
-	err := m.DB.QueryRow(queries.PurchaseOrderDetails, oid).Scan(&id, &orderDate, &supplier, &warehouse, &priceBeforeDiscount, &discountType, &discountAmount, &totalPrice, &remarks)
+	var id, orderDate, supplier, warehouse, priceBeforeDiscount, discountType, discountAmount, totalPrice, remarks, currencyCode, exchangeRate, status, closedBy, closedOn, closeReason, approvalStatus, approvedBy, approvedOn sql.NullString
+	err := m.DB.QueryRow(queries.PurchaseOrderDetails, oid).Scan(&id, &orderDate, &supplier, &warehouse, &priceBeforeDiscount, &discountType, &discountAmount, &totalPrice, &remarks, &currencyCode, &exchangeRate, &status, &closedBy, &closedOn, &closeReason, &approvalStatus, &approvedBy, &approvedOn)
 
 	if err != nil {
 		return models.PurchaseOrderSummary{}, err
@@ -122,12 +397,12 @@ func (m *PurchaseOrderModel) PurchaseOrderDetails(oid int) (models.PurchaseOrder
 		return models.PurchaseOrderSummary{}, err
 	}
 
-	return models.PurchaseOrderSummary{OrderID: id, OrderDate: orderDate, Supplier: supplier, Warehouse: warehouse, PriceBeforeDiscount: priceBeforeDiscount, DiscountType: discountType, DiscountAmount: discountAmount, TotalPrice: totalPrice, Remarks: remarks, OrderItemDetails: orderItems}, nil
+	return models.PurchaseOrderSummary{OrderID: id, OrderDate: orderDate, Supplier: supplier, Warehouse: warehouse, PriceBeforeDiscount: priceBeforeDiscount, DiscountType: discountType, DiscountAmount: discountAmount, TotalPrice: totalPrice, Remarks: remarks, CurrencyCode: currencyCode, ExchangeRate: exchangeRate, Status: status, ClosedBy: closedBy, ClosedOn: closedOn, CloseReason: closeReason, ApprovalStatus: approvalStatus, ApprovedBy: approvedBy, ApprovedOn: approvedOn, OrderItemDetails: orderItems}, nil
 }
 
 func (m *PurchaseOrderModel) PurchaseOrderData(oid int) (models.PurchaseOrderData, error) {
-	var id, supplierId, warehouseId, discountType, discountAmount sql.NullString
-	err := m.DB.QueryRow(queries.PurchaseOrderData, oid).Scan(&id, &supplierId, &warehouseId, &discountType, &discountAmount)
+	var id, supplierId, warehouseId, discountType, discountAmount, currencyCode, exchangeRate sql.NullString
+	err := m.DB.QueryRow(queries.PurchaseOrderData, oid).Scan(&id, &supplierId, &warehouseId, &discountType, &discountAmount, &currencyCode, &exchangeRate)
 
 	if err != nil {
 		return models.PurchaseOrderData{}, err
@@ -139,5 +414,5 @@ func (m *PurchaseOrderModel) PurchaseOrderData(oid int) (models.PurchaseOrderDat
 		return models.PurchaseOrderData{}, err
 	}
 
-	return models.PurchaseOrderData{OrderID: id, SupplierID: supplierId, WarehouseID: warehouseId, DiscountType: discountType, DiscountAmount: discountAmount, OrderItemData: orderItems}, nil
+	return models.PurchaseOrderData{OrderID: id, SupplierID: supplierId, WarehouseID: warehouseId, DiscountType: discountType, DiscountAmount: discountAmount, CurrencyCode: currencyCode, ExchangeRate: exchangeRate, OrderItemData: orderItems}, nil
 }
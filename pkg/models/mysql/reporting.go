@@ -2,9 +2,12 @@ package mysql
 
 import (
 	"database/sql"
+	"math"
+
 	"github.com/ssrdive/basara/pkg/models"
 	"github.com/ssrdive/basara/pkg/sql/queries"
 	"github.com/ssrdive/mysequel"
+	"github.com/ssrdive/scribe"
 )
 
 // ReportingModel struct holds database instance
@@ -24,3 +27,83 @@ func (m *ReportingModel) InvoiceSearch(startDate, endDate, officer string) ([]mo
 
 	return res, nil
 }
+
+// InventoryValuation returns the stock quantity and value per item and warehouse
+// as at the end of the given date, reconciled against the stock account balance
+func (m *ReportingModel) InventoryValuation(date string) (models.InventoryValuation, error) {
+	var items []models.InventoryValuationItem
+	err := mysequel.QueryToStructs(&items, m.DB, queries.InventoryValuation, date, date, date, date, date, date, date, date, date, date)
+	if err != nil {
+		return models.InventoryValuation{}, err
+	}
+
+	valuation := models.InventoryValuation{Date: date, Items: items}
+	for _, item := range items {
+		valuation.TotalCostValue = valuation.TotalCostValue + item.CostValue
+		valuation.TotalLandedCostValue = valuation.TotalLandedCostValue + item.LandedCostValue
+	}
+
+	account := scribe.AccountModel{DB: m.DB}
+	trialBalance, err := account.TrialBalance(date)
+	if err != nil {
+		return models.InventoryValuation{}, err
+	}
+
+	rows, err := m.DB.Query(queries.PostingRuleAccounts, PostingRuleStock)
+	if err != nil {
+		return models.InventoryValuation{}, err
+	}
+	defer rows.Close()
+
+	stockAccounts := make(map[int]bool)
+	for rows.Next() {
+		var accountID int
+		err = rows.Scan(&accountID)
+		if err != nil {
+			return models.InventoryValuation{}, err
+		}
+		stockAccounts[accountID] = true
+	}
+
+	for _, entry := range trialBalance {
+		if stockAccounts[entry.ID] {
+			valuation.StockAccountBalance = valuation.StockAccountBalance + entry.Debit - entry.Credit
+		}
+	}
+
+	valuation.TotalCostValue = math.Round(valuation.TotalCostValue*100) / 100
+	valuation.TotalLandedCostValue = math.Round(valuation.TotalLandedCostValue*100) / 100
+	valuation.Difference = math.Round((valuation.TotalCostValue-valuation.StockAccountBalance)*100) / 100
+	valuation.Reconciled = valuation.Difference == 0
+
+	return valuation, nil
+}
+
+// ReorderSuggestions compares the reorder levels with the stock on hand, in transit
+// and on order and suggests purchase quantities grouped by the last supplier of the item
+func (m *ReportingModel) ReorderSuggestions(warehouseID string) ([]models.ReorderSupplierSuggestions, error) {
+	w := mysequel.NewNullString(warehouseID)
+
+	var levels []models.ReorderLevelStatus
+	err := mysequel.QueryToStructs(&levels, m.DB, queries.ReorderLevelStatus, w, w)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []models.ReorderSupplierSuggestions
+	for _, level := range levels {
+		available := level.OnHand + level.InTransit + level.OnOrder
+		if available > level.MinQty || level.MaxQty <= available {
+			continue
+		}
+
+		if len(res) == 0 || res[len(res)-1].SupplierID != level.SupplierID {
+			res = append(res, models.ReorderSupplierSuggestions{SupplierID: level.SupplierID, Supplier: level.Supplier})
+		}
+		group := &res[len(res)-1]
+
+		group.Items = append(group.Items, models.ReorderSuggestion{ReorderLevelStatus: level, SuggestedQty: level.MaxQty - available})
+	}
+
+	return res, nil
+}
@@ -0,0 +1,254 @@
+package mysql
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/ssrdive/basara/pkg/models"
+	"github.com/ssrdive/basara/pkg/sql/queries"
+	"github.com/ssrdive/mysequel"
+	"github.com/ssrdive/scribe"
+	smodels "github.com/ssrdive/scribe/models"
+)
+
+// StockAdjustmentModel struct holds database instance
+type StockAdjustmentModel struct {
+	DB *sql.DB
+}
+
+// CreateStockAdjustment creates a stock adjustment for damaged, lost or found stock
+func (m *StockAdjustmentModel) CreateStockAdjustment(rparams, oparams []string, form url.Values) (int64, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+			return
+		}
+		_ = tx.Commit()
+	}()
+
+	var entries []models.StockAdjustmentEntry
+	err = json.Unmarshal([]byte(form.Get("entries")), &entries)
+	if err != nil {
+		return 0, err
+	}
+
+	said, err := issueStockAdjustment(tx, form.Get("user_id"), form.Get("warehouse_id"), form.Get("remark"), entries)
+	if err != nil {
+		return 0, err
+	}
+
+	return said, nil
+}
+
+// stockAdjustmentPosting holds the net value adjusted between a stock account
+// and the account of an adjustment reason
+type stockAdjustmentPosting struct {
+	StockAccountID int
+	AccountID      int
+	Value          float64
+}
+
+// issueStockAdjustment applies the adjustment entries to the current stock of a warehouse
+// and posts the value difference to the account configured for each reason
+func issueStockAdjustment(tx *sql.Tx, userID, warehouseID, remark string, entries []models.StockAdjustmentEntry) (int64, error) {
+	if len(entries) == 0 {
+		return 0, errors.New("stock adjustment has no entries")
+	}
+
+	said, err := mysequel.Insert(mysequel.Table{
+		TableName: "stock_adjustment",
+		Columns:   []string{"user_id", "warehouse_id", "remarks"},
+		Vals:      []interface{}{userID, warehouseID, remark},
+		Tx:        tx,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	// Net value adjusted between each stock account and reason
+	// account, positive values being stock gains
+	var postings []stockAdjustmentPosting
+
+	for _, entry := range entries {
+		qty, _ := strconv.Atoi(entry.Quantity)
+		if qty == 0 {
+			return 0, errors.New("invalid adjustment quantity")
+		}
+
+		var accountID sql.NullInt32
+		err = tx.QueryRow("SELECT account_id FROM stock_adjustment_reason WHERE id = ?", entry.ReasonID).Scan(&accountID)
+		if err != nil {
+			return 0, err
+		}
+
+		if !accountID.Valid {
+			return 0, errors.New("account for stock adjustment reason is not configured")
+		}
+
+		var value float64
+		if qty > 0 {
+			value, err = addFoundStock(tx, said, warehouseID, entry, qty)
+		} else {
+			value, err = removeStock(tx, said, warehouseID, entry, -qty)
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		var stockAccountID int
+		stockAccountID, err = itemStockAccount(tx, entry.ItemID)
+		if err != nil {
+			return 0, err
+		}
+
+		found := false
+		for i := range postings {
+			if postings[i].StockAccountID == stockAccountID && postings[i].AccountID == int(accountID.Int32) {
+				postings[i].Value = postings[i].Value + value
+				found = true
+			}
+		}
+		if !found {
+			postings = append(postings, stockAdjustmentPosting{StockAccountID: stockAccountID, AccountID: int(accountID.Int32), Value: value})
+		}
+	}
+
+	tid, err := createTransaction(tx, userID, time.Now().Format("2006-01-02"), fmt.Sprintf("STOCK ADJUSTMENT %d", said))
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = mysequel.Update(mysequel.UpdateTable{
+		Table: mysequel.Table{
+			TableName: "stock_adjustment",
+			Columns:   []string{"transaction_id"},
+			Vals:      []interface{}{tid},
+			Tx:        tx,
+		},
+		WColumns: []string{"id"},
+		WVals:    []string{strconv.FormatInt(said, 10)},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var journalEntries []smodels.JournalEntry
+	for _, posting := range postings {
+		value := math.Round(posting.Value*100) / 100
+		if value > 0 {
+			journalEntries = append(journalEntries,
+				smodels.JournalEntry{Account: fmt.Sprintf("%d", posting.StockAccountID), Debit: fmt.Sprintf("%f", value), Credit: ""},
+				smodels.JournalEntry{Account: fmt.Sprintf("%d", posting.AccountID), Debit: "", Credit: fmt.Sprintf("%f", value)},
+			)
+		} else if value < 0 {
+			journalEntries = append(journalEntries,
+				smodels.JournalEntry{Account: fmt.Sprintf("%d", posting.AccountID), Debit: fmt.Sprintf("%f", -value), Credit: ""},
+				smodels.JournalEntry{Account: fmt.Sprintf("%d", posting.StockAccountID), Debit: "", Credit: fmt.Sprintf("%f", -value)},
+			)
+		}
+	}
+
+	err = scribe.IssueJournalEntries(tx, tid, journalEntries)
+	if err != nil {
+		return 0, err
+	}
+
+	return said, nil
+}
+
+// removeStock takes the quantity out of the warehouse giving priority to the
+// oldest goods received notes and returns the cost of the removed stock
+func removeStock(tx *sql.Tx, said int64, warehouseID string, entry models.StockAdjustmentEntry, qty int) (float64, error) {
+	var stockItems []models.WarehouseItemStockWithDocumentIDsAndPrices
+	err := mysequel.QueryToStructs(&stockItems, tx, queries.WarehouseItemStockWithDocumentIdsAndPrices, warehouseID, entry.ItemID)
+	if err != nil {
+		return 0, err
+	}
+
+	var value float64
+	for _, stockItem := range stockItems {
+		subtractQty := stockItem.Qty
+		if qty < stockItem.Qty {
+			subtractQty = qty
+		}
+		qty = qty - subtractQty
+
+		res, err := tx.Exec("UPDATE current_stock SET qty = qty - ? WHERE entry_specifier = ? AND qty >= ?", subtractQty, stockItem.EntrySpecifier, subtractQty)
+		if err != nil {
+			return 0, err
+		}
+
+		rowsAffected, err := res.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+
+		if rowsAffected != 1 {
+			return 0, fmt.Errorf("current stock entry %s could not be updated", stockItem.EntrySpecifier)
+		}
+
+		err = insertStockAdjustmentItem(tx, said, entry.ReasonID, stockItem, -subtractQty)
+		if err != nil {
+			return 0, err
+		}
+
+		value = value - (stockItem.CostPriceWithoutLandedCosts * float64(subtractQty))
+
+		if qty == 0 {
+			break
+		}
+	}
+
+	if qty != 0 {
+		return 0, fmt.Errorf("adjustment quantity for item %s is higher than the present quantity", entry.ItemID)
+	}
+
+	return value, nil
+}
+
+// addFoundStock adds the quantity to the latest stock entry of the item in the warehouse
+// so that it is valued at the most recent cost and returns the value of the added stock
+func addFoundStock(tx *sql.Tx, said int64, warehouseID string, entry models.StockAdjustmentEntry, qty int) (float64, error) {
+	var stockItems []models.WarehouseItemStockWithDocumentIDsAndPrices
+	err := mysequel.QueryToStructs(&stockItems, tx, queries.LatestWarehouseItemStock, warehouseID, entry.ItemID)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(stockItems) == 0 {
+		return 0, fmt.Errorf("item %s has never been stocked in the warehouse", entry.ItemID)
+	}
+
+	stockItem := stockItems[0]
+	_, err = tx.Exec("UPDATE current_stock SET qty = qty + ? WHERE entry_specifier = ?", qty, stockItem.EntrySpecifier)
+	if err != nil {
+		return 0, err
+	}
+
+	err = insertStockAdjustmentItem(tx, said, entry.ReasonID, stockItem, qty)
+	if err != nil {
+		return 0, err
+	}
+
+	return stockItem.CostPriceWithoutLandedCosts * float64(qty), nil
+}
+
+func insertStockAdjustmentItem(tx *sql.Tx, said int64, reasonID string, stockItem models.WarehouseItemStockWithDocumentIDsAndPrices, qty int) error {
+	_, err := mysequel.Insert(mysequel.Table{
+		TableName: "stock_adjustment_item",
+		Columns:   []string{"stock_adjustment_id", "stock_adjustment_reason_id", "entry_specifier", "item_id", "goods_received_note_id", "inventory_transfer_id", "qty", "cost_price"},
+		Vals:      []interface{}{said, reasonID, stockItem.EntrySpecifier, stockItem.ItemID, stockItem.GoodsReceivedNoteID, nullInt32Value(stockItem.InventoryTransferID), qty, stockItem.CostPriceWithoutLandedCosts},
+		Tx:        tx,
+	})
+	return err
+}
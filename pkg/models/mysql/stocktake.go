@@ -0,0 +1,290 @@
+package mysql
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/ssrdive/basara/pkg/models"
+	"github.com/ssrdive/basara/pkg/sql/queries"
+	"github.com/ssrdive/mysequel"
+)
+
+// StockTakeModel struct holds database instance
+type StockTakeModel struct {
+	DB *sql.DB
+}
+
+// CreateStockTake starts a stock take by freezing a snapshot of the warehouse stock
+func (m *StockTakeModel) CreateStockTake(rparams, oparams []string, form url.Values) (int64, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+			return
+		}
+		_ = tx.Commit()
+	}()
+
+	var openStockTakes int
+	err = tx.QueryRow("SELECT COUNT(*) FROM stock_take WHERE warehouse_id = ? AND status = 'Open' FOR UPDATE", form.Get("warehouse_id")).Scan(&openStockTakes)
+	if err != nil {
+		return 0, err
+	}
+
+	if openStockTakes != 0 {
+		err = errors.New("warehouse already has an open stock take")
+		return 0, err
+	}
+
+	stid, err := mysequel.Insert(mysequel.Table{
+		TableName: "stock_take",
+		Columns:   []string{"user_id", "warehouse_id", "status", "remarks"},
+		Vals:      []interface{}{form.Get("user_id"), form.Get("warehouse_id"), "Open", form.Get("remark")},
+		Tx:        tx,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var warehouseStock []models.WarehouseStockItem
+	err = mysequel.QueryToStructs(&warehouseStock, tx, queries.WarehouseStock, form.Get("warehouse_id"))
+	if err != nil {
+		return 0, err
+	}
+
+	for _, stockItem := range warehouseStock {
+		_, err = mysequel.Insert(mysequel.Table{
+			TableName: "stock_take_item",
+			Columns:   []string{"stock_take_id", "item_id", "snapshot_qty"},
+			Vals:      []interface{}{stid, stockItem.ID, stockItem.Quantity},
+			Tx:        tx,
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return stid, nil
+}
+
+// SubmitCount records the quantities counted in a single counting session.
+// Counts of the same item across sessions are added together.
+func (m *StockTakeModel) SubmitCount(rparams, oparams []string, form url.Values) (int64, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+			return
+		}
+		_ = tx.Commit()
+	}()
+
+	var status string
+	err = tx.QueryRow("SELECT status FROM stock_take WHERE id = ? FOR UPDATE", form.Get("stock_take_id")).Scan(&status)
+	if err != nil {
+		return 0, err
+	}
+
+	if status != "Open" {
+		err = errors.New("stock take is not open for counting")
+		return 0, err
+	}
+
+	var countItems []models.TransferItem
+	err = json.Unmarshal([]byte(form.Get("entries")), &countItems)
+	if err != nil {
+		return 0, err
+	}
+
+	var session int64
+	err = tx.QueryRow("SELECT COALESCE(MAX(session), 0) + 1 FROM stock_take_count WHERE stock_take_id = ?", form.Get("stock_take_id")).Scan(&session)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, countItem := range countItems {
+		qty, convErr := strconv.Atoi(countItem.Quantity)
+		if convErr != nil || qty < 0 {
+			err = errors.New("invalid counted quantity")
+			return 0, err
+		}
+
+		_, err = mysequel.Insert(mysequel.Table{
+			TableName: "stock_take_count",
+			Columns:   []string{"stock_take_id", "session", "item_id", "counted_qty", "user_id"},
+			Vals:      []interface{}{form.Get("stock_take_id"), session, countItem.ItemID, qty, form.Get("user_id")},
+			Tx:        tx,
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return session, nil
+}
+
+// Variance returns the counted quantities of a stock take against the stock now on hand.
+// The snapshot is returned for reference only as stock may have moved since it was taken
+func (m *StockTakeModel) Variance(stid int) ([]models.StockTakeVariance, error) {
+	var res []models.StockTakeVariance
+	err := mysequel.QueryToStructs(&res, m.DB, queries.StockTakeVariance, stid, stid, stid, stid)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// List returns all stock takes
+func (m *StockTakeModel) List() ([]models.StockTakeEntry, error) {
+	var res []models.StockTakeEntry
+	err := mysequel.QueryToStructs(&res, m.DB, queries.StockTakeList)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// Approve closes a stock take and adjusts the warehouse stock by the difference between
+// the counted quantities and the stock on hand when approving, so that movements made
+// while the stock take was open are not adjusted again. Items in the snapshot that were
+// never counted are treated as missing.
+func (m *StockTakeModel) Approve(rparams, oparams []string, form url.Values) (int64, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+			return
+		}
+		_ = tx.Commit()
+	}()
+
+	var warehouseID int
+	var status string
+	err = tx.QueryRow("SELECT warehouse_id, status FROM stock_take WHERE id = ? FOR UPDATE", form.Get("stock_take_id")).Scan(&warehouseID, &status)
+	if err != nil {
+		return 0, err
+	}
+
+	if status != "Open" {
+		err = errors.New("stock take is not open")
+		return 0, err
+	}
+
+	// Lock the warehouse stock so that it cannot move between
+	// reading the on hand quantities and adjusting them
+	rows, err := tx.Query("SELECT entry_specifier FROM current_stock WHERE warehouse_id = ? FOR UPDATE", warehouseID)
+	if err != nil {
+		return 0, err
+	}
+	err = rows.Close()
+	if err != nil {
+		return 0, err
+	}
+
+	stid := form.Get("stock_take_id")
+	var variances []models.StockTakeVariance
+	err = mysequel.QueryToStructs(&variances, tx, queries.StockTakeVariance, stid, stid, stid, stid)
+	if err != nil {
+		return 0, err
+	}
+
+	var entries []models.StockAdjustmentEntry
+	for _, variance := range variances {
+		if variance.Variance == 0 {
+			continue
+		}
+		entries = append(entries, models.StockAdjustmentEntry{
+			ItemID:   strconv.Itoa(variance.ID),
+			Quantity: strconv.Itoa(variance.Variance),
+			ReasonID: form.Get("reason_id"),
+		})
+	}
+
+	var said int64
+	if len(entries) != 0 {
+		said, err = issueStockAdjustment(tx, form.Get("user_id"), strconv.Itoa(warehouseID), fmt.Sprintf("STOCK TAKE %s", stid), entries)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	var stockAdjustmentID interface{} = ""
+	if said != 0 {
+		stockAdjustmentID = said
+	}
+
+	_, err = mysequel.Update(mysequel.UpdateTable{
+		Table: mysequel.Table{
+			TableName: "stock_take",
+			Columns:   []string{"status", "approved_by", "approved_on", "stock_adjustment_id"},
+			Vals:      []interface{}{"Approved", form.Get("user_id"), time.Now().Format("2006-01-02 15:04:05"), stockAdjustmentID},
+			Tx:        tx,
+		},
+		WColumns: []string{"id"},
+		WVals:    []string{stid},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return said, nil
+}
+
+// Cancel abandons an open stock take without adjusting the warehouse stock so
+// that a new stock take can be started for the warehouse
+func (m *StockTakeModel) Cancel(rparams, oparams []string, form url.Values) (int64, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+			return
+		}
+		_ = tx.Commit()
+	}()
+
+	var status string
+	err = tx.QueryRow("SELECT status FROM stock_take WHERE id = ? FOR UPDATE", form.Get("stock_take_id")).Scan(&status)
+	if err != nil {
+		return 0, err
+	}
+
+	if status != "Open" {
+		err = errors.New("stock take is not open")
+		return 0, err
+	}
+
+	_, err = mysequel.Update(mysequel.UpdateTable{
+		Table: mysequel.Table{
+			TableName: "stock_take",
+			Columns:   []string{"status", "cancelled_by", "cancelled_on", "cancel_remarks"},
+			Vals:      []interface{}{"Cancelled", form.Get("user_id"), time.Now().Format("2006-01-02 15:04:05"), form.Get("remark")},
+			Tx:        tx,
+		},
+		WColumns: []string{"id"},
+		WVals:    []string{form.Get("stock_take_id")},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.ParseInt(form.Get("stock_take_id"), 10, 64)
+}
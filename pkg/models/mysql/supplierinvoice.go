@@ -0,0 +1,393 @@
+package mysql
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/ssrdive/basara/pkg/models"
+	"github.com/ssrdive/basara/pkg/sql/queries"
+	"github.com/ssrdive/mysequel"
+	"github.com/ssrdive/scribe"
+)
+
+// SupplierInvoiceModel struct holds database instance
+type SupplierInvoiceModel struct {
+	DB *sql.DB
+}
+
+// CreateSupplierInvoice records a supplier invoice against goods received notes and matches
+// its lines with the received quantities and the goods received note and purchase order prices.
+// Fully matched invoices are posted to the supplier straight away
+func (m *SupplierInvoiceModel) CreateSupplierInvoice(rparams, oparams []string, form url.Values) (int64, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+			return
+		}
+		_ = tx.Commit()
+	}()
+
+	var invoiceItems []models.SupplierInvoiceItemEntry
+	err = json.Unmarshal([]byte(form.Get("entries")), &invoiceItems)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(invoiceItems) == 0 {
+		err = errors.New("supplier invoice has no items")
+		return 0, err
+	}
+
+	dueDate := form.Get("due_date")
+	if dueDate == "" {
+		dueDate = form.Get("invoice_date")
+	}
+
+	currencyCode, exchangeRate, err := documentCurrency(form)
+	if err != nil {
+		return 0, err
+	}
+
+	siid, err := mysequel.Insert(mysequel.Table{
+		TableName: "supplier_invoice",
+		Columns:   []string{"user_id", "supplier_id", "invoice_number", "invoice_date", "due_date", "currency_code", "exchange_rate", "total_amount", "grn_value", "status", "remarks"},
+		Vals:      []interface{}{form.Get("user_id"), form.Get("supplier_id"), form.Get("invoice_number"), form.Get("invoice_date"), dueDate, currencyCode, exchangeRate, 0, 0, "Mismatch", form.Get("remark")},
+		Tx:        tx,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var totalAmount, grnValue float64
+	matched := true
+	for _, invoiceItem := range invoiceItems {
+		var qty, unitPrice float64
+		qty, err = strconv.ParseFloat(invoiceItem.Quantity, 32)
+		if err != nil {
+			return 0, err
+		}
+
+		unitPrice, err = strconv.ParseFloat(invoiceItem.UnitPrice, 32)
+		if err != nil {
+			return 0, err
+		}
+
+		if qty <= 0 {
+			err = errors.New("invalid invoice quantity")
+			return 0, err
+		}
+
+		var grnItem models.SupplierInvoiceGRNItem
+		err = tx.QueryRow(queries.SupplierInvoiceGrnItem, invoiceItem.GRNID, invoiceItem.ItemID).Scan(&grnItem.SupplierID, &grnItem.LandedCostID, &grnItem.CurrencyCode, &grnItem.ExchangeRate, &grnItem.Qty, &grnItem.UnitPrice, &grnItem.OrderUnitPrice, &grnItem.InvoicedQty)
+		if err == sql.ErrNoRows {
+			err = fmt.Errorf("item %s is not on goods received note %s", invoiceItem.ItemID, invoiceItem.GRNID)
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		if strconv.Itoa(grnItem.SupplierID) != form.Get("supplier_id") {
+			err = fmt.Errorf("goods received note %s is not from the supplier", invoiceItem.GRNID)
+			return 0, err
+		}
+
+		if grnItem.LandedCostID == 0 {
+			err = fmt.Errorf("goods received note %s is not costed yet", invoiceItem.GRNID)
+			return 0, err
+		}
+
+		if grnItem.CurrencyCode != currencyCode {
+			err = fmt.Errorf("goods received note %s is not in the invoice currency", invoiceItem.GRNID)
+			return 0, err
+		}
+
+		qtyVariance := qty - (grnItem.Qty - grnItem.InvoicedQty)
+		priceVariance := unitPrice - grnItem.UnitPrice
+
+		matchStatus := "Matched"
+		if qtyVariance > 0 || math.Abs(priceVariance) > 0.005 || math.Abs(unitPrice-grnItem.OrderUnitPrice) > 0.005 {
+			matchStatus = "Mismatch"
+			matched = false
+		}
+
+		_, err = mysequel.Insert(mysequel.Table{
+			TableName: "supplier_invoice_item",
+			Columns:   []string{"supplier_invoice_id", "goods_received_note_id", "item_id", "qty", "unit_price", "total_price", "grn_unit_price", "order_unit_price", "qty_variance", "price_variance", "match_status"},
+			Vals:      []interface{}{siid, invoiceItem.GRNID, invoiceItem.ItemID, qty, unitPrice, qty * unitPrice, grnItem.UnitPrice, grnItem.OrderUnitPrice, math.Max(qtyVariance, 0), priceVariance, matchStatus},
+			Tx:        tx,
+		})
+		if err != nil {
+			return 0, err
+		}
+
+		totalAmount = totalAmount + (qty * unitPrice)
+		grnValue = grnValue + (qty * grnItem.UnitPrice * grnItem.ExchangeRate)
+	}
+
+	_, err = tx.Exec("UPDATE supplier_invoice SET total_amount = ?, grn_value = ? WHERE id = ?", math.Round(totalAmount*100)/100, math.Round(grnValue*100)/100, siid)
+	if err != nil {
+		return 0, err
+	}
+
+	if matched {
+		err = postSupplierInvoice(tx, form.Get("user_id"), siid)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return siid, nil
+}
+
+// PostSupplierInvoice posts a supplier invoice with unmatched lines after review.
+// It needs an approver other than the user who entered the invoice whose supplier
+// invoice limit covers the invoice value. The difference between the invoice and
+// the goods received note value is posted to stock and cost of sales
+func (m *SupplierInvoiceModel) PostSupplierInvoice(form url.Values) (int64, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+			return
+		}
+		_ = tx.Commit()
+	}()
+
+	siid, err := strconv.ParseInt(form.Get("supplier_invoice_id"), 10, 64)
+	if err != nil {
+		return 0, err
+	}
+
+	var createdBy, status string
+	var totalAmount float64
+	err = tx.QueryRow(queries.SupplierInvoiceForApproval, siid).Scan(&createdBy, &status, &totalAmount)
+	if err != nil {
+		return 0, err
+	}
+
+	if status != "Mismatch" {
+		err = errors.New("supplier invoice is not awaiting approval")
+		return 0, err
+	}
+
+	if createdBy == form.Get("user_id") {
+		err = errors.New("supplier invoice cannot be approved by the user who entered it")
+		return 0, err
+	}
+
+	var limit float64
+	err = tx.QueryRow(queries.UserSupplierInvoiceApprovalLimit, form.Get("user_id")).Scan(&limit)
+	if err == sql.ErrNoRows || (err == nil && limit < totalAmount) {
+		err = errors.New("insufficient approval authority")
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec("UPDATE supplier_invoice SET approved_by = ?, approved_on = NOW(), approval_remarks = ? WHERE id = ?", form.Get("user_id"), form.Get("remark"), siid)
+	if err != nil {
+		return 0, err
+	}
+
+	err = postSupplierInvoice(tx, form.Get("user_id"), siid)
+	if err != nil {
+		return 0, err
+	}
+
+	return siid, nil
+}
+
+// Reject rejects a supplier invoice that has not been posted so that the quantities
+// it was matched against can be invoiced again
+func (m *SupplierInvoiceModel) Reject(rparams, oparams []string, form url.Values) (int64, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+			return
+		}
+		_ = tx.Commit()
+	}()
+
+	var status string
+	err = tx.QueryRow("SELECT status FROM supplier_invoice WHERE id = ? FOR UPDATE", form.Get("supplier_invoice_id")).Scan(&status)
+	if err != nil {
+		return 0, err
+	}
+
+	if status == "Posted" {
+		err = errors.New("posted supplier invoice cannot be rejected")
+		return 0, err
+	}
+
+	if status == "Rejected" {
+		err = errors.New("supplier invoice is already rejected")
+		return 0, err
+	}
+
+	_, err = mysequel.Update(mysequel.UpdateTable{
+		Table: mysequel.Table{
+			TableName: "supplier_invoice",
+			Columns:   []string{"status", "rejected_by", "rejected_on", "reject_remarks"},
+			Vals:      []interface{}{"Rejected", form.Get("user_id"), time.Now().Format("2006-01-02 15:04:05"), form.Get("remark")},
+			Tx:        tx,
+		},
+		WColumns: []string{"id"},
+		WVals:    []string{form.Get("supplier_invoice_id")},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.ParseInt(form.Get("supplier_invoice_id"), 10, 64)
+}
+
+// List returns all supplier invoices
+func (m *SupplierInvoiceModel) List() ([]models.SupplierInvoiceEntry, error) {
+	var res []models.SupplierInvoiceEntry
+	err := mysequel.QueryToStructs(&res, m.DB, queries.SupplierInvoiceList)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// Details returns a supplier invoice with its lines and match results
+func (m *SupplierInvoiceModel) Details(siid int) (models.SupplierInvoiceSummary, error) {
+	var res models.SupplierInvoiceSummary
+	err := m.DB.QueryRow(queries.SupplierInvoiceDetails, siid).Scan(&res.ID, &res.Supplier, &res.InvoiceNumber, &res.InvoiceDate, &res.DueDate, &res.TotalAmount, &res.GRNValue, &res.Status)
+	if err != nil {
+		return models.SupplierInvoiceSummary{}, err
+	}
+
+	err = mysequel.QueryToStructs(&res.Items, m.DB, queries.SupplierInvoiceItems, siid)
+	if err != nil {
+		return models.SupplierInvoiceSummary{}, err
+	}
+
+	return res, nil
+}
+
+func postSupplierInvoice(tx *sql.Tx, userID string, siid int64) error {
+	var supplierID int
+	var invoiceNumber, invoiceDate, status, currencyCode string
+	var totalAmount, grnValue, exchangeRate float64
+	err := tx.QueryRow(queries.SupplierInvoiceForPosting, siid).Scan(&supplierID, &invoiceNumber, &invoiceDate, &totalAmount, &grnValue, &status, &currencyCode, &exchangeRate)
+	if err != nil {
+		return err
+	}
+
+	if status == "Posted" {
+		return errors.New("supplier invoice is already posted")
+	}
+
+	if status == "Rejected" {
+		return errors.New("supplier invoice is rejected")
+	}
+
+	tid, err := createTransaction(tx, userID, time.Now().Format("2006-01-02"), fmt.Sprintf("SUPPLIER INVOICE %d [%s]", siid, invoiceNumber))
+	if err != nil {
+		return err
+	}
+
+	baseAmount := math.Round(totalAmount*exchangeRate*100) / 100
+	_, err = mysequel.Insert(mysequel.Table{
+		TableName: "business_partner_financial",
+		Columns:   []string{"effective_date", "business_partner_id", "type", "amount", "transaction_id", "currency_code", "foreign_amount", "exchange_rate"},
+		Vals:      []interface{}{invoiceDate, supplierID, "CR", baseAmount, tid, currencyCode, totalAmount, exchangeRate},
+		Tx:        tx,
+	})
+	if err != nil {
+		return err
+	}
+
+	// The goods received note value is already on the payable account from
+	// costing, only the difference accepted on the invoice is posted here
+	difference := math.Round((baseAmount-grnValue)*100) / 100
+	if difference != 0 {
+		err = postSupplierInvoiceVariance(tx, tid, siid, exchangeRate, difference)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec("UPDATE supplier_invoice SET status = 'Posted', transaction_id = ?, posted_by = ?, posted_on = NOW() WHERE id = ?", tid, userID, siid)
+	return err
+}
+
+// postSupplierInvoiceVariance spreads the difference between the invoice and the goods
+// received note value of each line over the received units. The share of the units
+// still in stock is added to the cost and price of their current stock entries and
+// the share of the units no longer in stock is posted to cost of sales
+func postSupplierInvoiceVariance(tx *sql.Tx, tid, siid int64, exchangeRate, difference float64) error {
+	var lines []models.SupplierInvoiceVarianceLine
+	err := mysequel.QueryToStructs(&lines, tx, queries.SupplierInvoiceVarianceLines, siid)
+	if err != nil {
+		return err
+	}
+
+	var amounts []accountAmount
+	for _, line := range lines {
+		lineDifference := (line.InvoiceAmount * exchangeRate) - line.GRNValue
+		if line.ReceivedQty <= 0 || math.Abs(lineDifference) < 0.005 {
+			continue
+		}
+
+		unitDifference := lineDifference / line.ReceivedQty
+		err = changeStockCost(tx, line.GoodsReceivedNoteID, line.ItemID, unitDifference, 0)
+		if err != nil {
+			return err
+		}
+
+		var stockAccountID, costOfSalesAccountID int
+		stockAccountID, err = stockAccount(tx, line.ItemCategoryID)
+		if err != nil {
+			return err
+		}
+
+		costOfSalesAccountID, err = requirePostingAccount(tx, PostingRuleCostOfSales, line.ItemCategoryID, strconv.Itoa(line.WarehouseID))
+		if err != nil {
+			return err
+		}
+
+		soldRatio := math.Max(line.ReceivedQty-line.RemainingQty, 0) / line.ReceivedQty
+		amounts = addAccountAmount(amounts, costOfSalesAccountID, lineDifference*soldRatio)
+		amounts = addAccountAmount(amounts, stockAccountID, lineDifference*(1-soldRatio))
+	}
+
+	// A difference made up only of rounding on the lines goes to stock
+	if len(amounts) == 0 {
+		var stockAccountID int
+		stockAccountID, err = stockAccount(tx, "")
+		if err != nil {
+			return err
+		}
+		amounts = addAccountAmount(amounts, stockAccountID, difference)
+	}
+
+	payableAccountID, err := payableAccount(tx)
+	if err != nil {
+		return err
+	}
+
+	journalEntries := accountAmountEntries(amounts, difference)
+	journalEntries = append(journalEntries, accountAmountEntries([]accountAmount{{AccountID: payableAccountID, Amount: -difference}}, -difference)...)
+	return scribe.IssueJournalEntries(tx, tid, journalEntries)
+}
@@ -0,0 +1,202 @@
+package mysql
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/ssrdive/basara/pkg/models"
+	"github.com/ssrdive/basara/pkg/sql/queries"
+	"github.com/ssrdive/mysequel"
+	"github.com/ssrdive/scribe"
+	smodels "github.com/ssrdive/scribe/models"
+)
+
+// SupplierReturnModel struct holds database instance
+type SupplierReturnModel struct {
+	DB *sql.DB
+}
+
+// CreateSupplierReturn returns unsold goods of a goods received note to the supplier
+// and issues a debit note for their cost. Only quantities on a posted supplier invoice
+// can be returned so that the debit note is always against an amount owed
+func (m *SupplierReturnModel) CreateSupplierReturn(rparams, oparams []string, form url.Values) (int64, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+			return
+		}
+		_ = tx.Commit()
+	}()
+
+	var supplierID, warehouseID int
+	var landedCostID sql.NullInt32
+	var currencyCode string
+	var exchangeRate float64
+	err = tx.QueryRow("SELECT supplier_id, warehouse_id, landed_cost_id, currency_code, exchange_rate FROM goods_received_note WHERE id = ? FOR UPDATE", form.Get("grn_id")).Scan(&supplierID, &warehouseID, &landedCostID, &currencyCode, &exchangeRate)
+	if err != nil {
+		return 0, err
+	}
+
+	if !landedCostID.Valid {
+		err = errors.New("goods received note is not costed yet")
+		return 0, err
+	}
+
+	var returnItems []models.TransferItem
+	err = json.Unmarshal([]byte(form.Get("entries")), &returnItems)
+	if err != nil {
+		return 0, err
+	}
+
+	srid, err := mysequel.Insert(mysequel.Table{
+		TableName: "supplier_return",
+		Columns:   []string{"user_id", "goods_received_note_id", "supplier_id", "warehouse_id", "total_price", "remarks"},
+		Vals:      []interface{}{form.Get("user_id"), form.Get("grn_id"), supplierID, warehouseID, 0, form.Get("remark")},
+		Tx:        tx,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var totalPrice float64
+	var stockAmounts []accountAmount
+	for _, returnItem := range returnItems {
+		itemQty, _ := strconv.Atoi(returnItem.Quantity)
+		if itemQty < 1 {
+			err = errors.New("invalid return quantity")
+			return 0, err
+		}
+
+		var returnableQty float64
+		err = tx.QueryRow(queries.GrnItemReturnableQty, form.Get("grn_id"), returnItem.ItemID, form.Get("grn_id"), returnItem.ItemID).Scan(&returnableQty)
+		if err != nil {
+			return 0, err
+		}
+
+		if float64(itemQty) > returnableQty {
+			err = fmt.Errorf("return quantity for item %s is higher than the quantity invoiced by the supplier", returnItem.ItemID)
+			return 0, err
+		}
+
+		// Only the quantities still available in the receiving
+		// warehouse can be sent back to the supplier
+		var itemValue float64
+		var stockEntries []models.GRNStockEntry
+		err = mysequel.QueryToStructs(&stockEntries, tx, queries.GrnItemUnsoldStock, form.Get("grn_id"), warehouseID, returnItem.ItemID)
+		if err != nil {
+			return 0, err
+		}
+
+		for _, stockEntry := range stockEntries {
+			subtractQty := stockEntry.Qty
+			if itemQty < stockEntry.Qty {
+				subtractQty = itemQty
+			}
+			itemQty = itemQty - subtractQty
+
+			var res sql.Result
+			res, err = tx.Exec("UPDATE current_stock SET qty = qty - ? WHERE entry_specifier = ? AND qty >= ?", subtractQty, stockEntry.EntrySpecifier, subtractQty)
+			if err != nil {
+				return 0, err
+			}
+
+			var rowsAffected int64
+			rowsAffected, err = res.RowsAffected()
+			if err != nil {
+				return 0, err
+			}
+
+			if rowsAffected != 1 {
+				err = fmt.Errorf("current stock entry %s could not be updated", stockEntry.EntrySpecifier)
+				return 0, err
+			}
+
+			_, err = mysequel.Insert(mysequel.Table{
+				TableName: "supplier_return_item",
+				Columns:   []string{"supplier_return_id", "entry_specifier", "item_id", "qty", "cost_price"},
+				Vals:      []interface{}{srid, stockEntry.EntrySpecifier, returnItem.ItemID, subtractQty, stockEntry.CostPrice},
+				Tx:        tx,
+			})
+			if err != nil {
+				return 0, err
+			}
+
+			itemValue = itemValue + (stockEntry.CostPrice * float64(subtractQty))
+
+			if itemQty == 0 {
+				break
+			}
+		}
+
+		if itemQty != 0 {
+			err = fmt.Errorf("return quantity for item %s is higher than the unsold quantity", returnItem.ItemID)
+			return 0, err
+		}
+
+		var stockAccountID int
+		stockAccountID, err = itemStockAccount(tx, returnItem.ItemID)
+		if err != nil {
+			return 0, err
+		}
+
+		stockAmounts = addAccountAmount(stockAmounts, stockAccountID, -itemValue)
+		totalPrice = totalPrice + itemValue
+	}
+
+	totalPrice = math.Round(totalPrice*100) / 100
+
+	tid, err := createTransaction(tx, form.Get("user_id"), time.Now().Format("2006-01-02"), fmt.Sprintf("SUPPLIER RETURN %d [GOODS RECEIVED NOTE %s]", srid, form.Get("grn_id")))
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = mysequel.Update(mysequel.UpdateTable{
+		Table: mysequel.Table{
+			TableName: "supplier_return",
+			Columns:   []string{"total_price", "transaction_id"},
+			Vals:      []interface{}{totalPrice, tid},
+			Tx:        tx,
+		},
+		WColumns: []string{"id"},
+		WVals:    []string{strconv.FormatInt(srid, 10)},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = mysequel.Insert(mysequel.Table{
+		TableName: "business_partner_financial",
+		Columns:   []string{"effective_date", "business_partner_id", "type", "amount", "transaction_id", "currency_code", "foreign_amount", "exchange_rate"},
+		Vals:      []interface{}{time.Now().Format("2006-01-02"), supplierID, "DR", totalPrice, tid, currencyCode, math.Round(totalPrice/exchangeRate*100) / 100, exchangeRate},
+		Tx:        tx,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	payableAccountID, err := payableAccount(tx)
+	if err != nil {
+		return 0, err
+	}
+
+	journalEntries := []smodels.JournalEntry{
+		{Account: fmt.Sprintf("%d", payableAccountID), Debit: fmt.Sprintf("%f", totalPrice), Credit: ""},
+	}
+	journalEntries = append(journalEntries, accountAmountEntries(stockAmounts, -totalPrice)...)
+	err = scribe.IssueJournalEntries(tx, tid, journalEntries)
+	if err != nil {
+		return 0, err
+	}
+
+	return srid, nil
+}
@@ -29,11 +29,6 @@ type Transactions struct {
 	TransactionsLogger *log.Logger
 }
 
-const (
-	SparePartsSalesAccountID       = 200
-	SparePartsCostOfSalesAccountID = 202
-)
-
 func (m *Transactions) GetSalesCommission(uid int) (models.CashInHand, error) {
 	var r models.CashInHand
 	err := m.DB.QueryRow(queries.GetSalesCommission, uid).Scan(&r.Amount)
@@ -119,6 +114,11 @@ func (m *Transactions) CreateInventoryTransfer(rparams, oparams []string, form u
 		}
 	}()
 
+	err = checkPostingPeriod(tx, form.Get("user_id"), time.Now().Format("2006-01-02"))
+	if err != nil {
+		return 0, err
+	}
+
 	// Converting JSON transfer entries to structs
 	entries := form.Get("entries")
 	m.TransactionsLogger.Printf("Transfer entries JSON: %s", entries)
@@ -232,7 +232,7 @@ func (m *Transactions) CreateInventoryTransfer(rparams, oparams []string, form u
 				subtractQty = stockItem.Qty
 				itemQty = itemQty - stockItem.Qty
 			}
-			m.TransactionsLogger.Printf("Subtracting %d from item ID %s (GoodsReceivedNoteID %s)", subtractQty, stockItem.ItemID, stockItem.GoodsReceivedNoteID)
+			m.TransactionsLogger.Printf("Subtracting %d from item ID %d (GoodsReceivedNoteID %d)", subtractQty, stockItem.ItemID, stockItem.GoodsReceivedNoteID)
 			transfers = append(transfers, models.WarehouseItemStockWithDocumentIDs{
 				EntrySpecifier:      stockItem.EntrySpecifier,
 				WarehouseID:         fromWarehouseID,
@@ -270,7 +270,7 @@ func (m *Transactions) CreateInventoryTransfer(rparams, oparams []string, form u
 	// float field for the transferring items based on the
 	// GRN selected. inventory_transfer_item is also populated
 	for _, transfer := range transfers {
-		m.TransactionsLogger.Printf("Processing transfer for item ID %s, GoodsReceivedNoteID %s, Quantity %d", transfer.ItemID, transfer.GoodsReceivedNoteID, transfer.Qty)
+		m.TransactionsLogger.Printf("Processing transfer for item ID %d, GoodsReceivedNoteID %d, Quantity %d", transfer.ItemID, transfer.GoodsReceivedNoteID, transfer.Qty)
 		if transfer.InventoryTransferID.Valid {
 			_, err = mysequel.Insert(mysequel.Table{
 				TableName: "inventory_transfer_item",
@@ -577,22 +577,47 @@ func (m *Transactions) CreateInvoice(rparams, oparams []string, apiKey string, f
 		return 0, err
 	}
 
-	tid, err := mysequel.Insert(mysequel.Table{
-		TableName: "transaction",
-		Columns:   []string{"user_id", "datetime", "posting_date", "remark"},
-		Vals:      []interface{}{form.Get("user_id"), time.Now().Format("2006-01-02 15:04:05"), time.Now().Format("2006-01-02"), fmt.Sprintf("INVOICE %d", iid)},
-		Tx:        tx,
+	tid, err := createTransaction(tx, form.Get("user_id"), time.Now().Format("2006-01-02"), fmt.Sprintf("INVOICE %d", iid))
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	_, err = mysequel.Update(mysequel.UpdateTable{
+		Table: mysequel.Table{
+			TableName: "invoice",
+			Columns:   []string{"transaction_id"},
+			Vals:      []interface{}{tid},
+			Tx:        tx,
+		},
+		WColumns: []string{"id"},
+		WVals:    []string{strconv.FormatInt(iid, 10)},
 	})
 	if err != nil {
 		tx.Rollback()
 		return 0, err
 	}
 
+	salesItems := make([]salesItem, len(invoice))
+	for i, item := range invoice {
+		salesItems[i] = salesItem{ItemID: item.ItemID, Price: item.Price * float64(item.Qty), Cost: item.CostPriceWithoutLandedCosts * float64(item.Qty)}
+	}
+
+	postings, err := salesPostings(tx, form.Get("from_warehouse"), salesItems, priceAfterDiscount, discount)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
 	journalEntries := []smodels.JournalEntry{
 		{Account: fmt.Sprintf("%d", cashAccountID.Int32), Debit: fmt.Sprintf("%f", priceAfterDiscount), Credit: ""},
-		{Account: fmt.Sprintf("%d", SparePartsSalesAccountID), Debit: "", Credit: fmt.Sprintf("%f", priceAfterDiscount)},
-		{Account: fmt.Sprintf("%d", SparePartsCostOfSalesAccountID), Debit: fmt.Sprintf("%f", costPriceWithoutLCs), Credit: ""},
-		{Account: fmt.Sprintf("%d", StockAccountID), Debit: "", Credit: fmt.Sprintf("%f", costPriceWithoutLCs)},
+	}
+	for _, posting := range postings {
+		journalEntries = append(journalEntries,
+			smodels.JournalEntry{Account: fmt.Sprintf("%d", posting.SalesAccountID), Debit: "", Credit: fmt.Sprintf("%f", posting.Price)},
+			smodels.JournalEntry{Account: fmt.Sprintf("%d", posting.CostOfSalesAccountID), Debit: fmt.Sprintf("%f", posting.Cost), Credit: ""},
+			smodels.JournalEntry{Account: fmt.Sprintf("%d", posting.StockAccountID), Debit: "", Credit: fmt.Sprintf("%f", posting.Cost)},
+		)
 	}
 	err = scribe.IssueJournalEntries(tx, tid, journalEntries)
 	if err != nil {
@@ -610,8 +635,10 @@ func (m *Transactions) CreateInvoice(rparams, oparams []string, apiKey string, f
 		tx.Rollback()
 		return 0, nil
 	} else {
-		resp, _ := http.Get(requestURL)
-		defer resp.Body.Close()
+		resp, err := http.Get(requestURL)
+		if err == nil {
+			defer resp.Body.Close()
+		}
 
 		tx.Commit()
 		return iid, nil
@@ -662,6 +689,197 @@ func (m *Transactions) InvoiceDetails(iid int) (models.InvoiceSummary, error) {
 	return invoiceSummary, nil
 }
 
+// CreateInvoiceReturn records a customer return against an existing invoice,
+// puts the returned quantities back into the current stock entries they were
+// sold from and posts the reversing journal entries
+func (m *Transactions) CreateInvoiceReturn(iid int, rparams, oparams []string, form url.Values) (int64, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			m.TransactionsLogger.Printf("CreateInvoiceReturn: rolling back invoice %d: %v", iid, err)
+			_ = tx.Rollback()
+			return
+		}
+		_ = tx.Commit()
+	}()
+
+	if form.Get("request_id") != "" {
+		if requestExists(tx, form.Get("request_id")) {
+			m.TransactionsLogger.Printf("Request dropped: %s", form.Get("request_id"))
+			return 0, nil
+		}
+
+		_, err = mysequel.Insert(mysequel.Table{
+			TableName: "unique_requests",
+			Columns:   []string{"request_id"},
+			Vals:      []interface{}{form.Get("request_id")},
+			Tx:        tx,
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	var returnItems []models.TransferItem
+	err = json.Unmarshal([]byte(form.Get("items")), &returnItems)
+	if err != nil {
+		return 0, err
+	}
+
+	var invoiceItems []models.InvoiceItemForReturn
+	err = mysequel.QueryToStructs(&invoiceItems, tx, queries.InvoiceItemsForReturn, iid, iid)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(invoiceItems) == 0 {
+		err = errors.New("invoice does not exist or has no items")
+		return 0, err
+	}
+
+	if invoiceItems[0].VoidedOn.Valid {
+		err = fmt.Errorf("invoice %d is voided and cannot be returned", iid)
+		return 0, err
+	}
+
+	// Returned quantities are allocated against the invoice lines of the
+	// item so that each unit goes back to the exact current stock entry
+	// it was taken from
+	var returnLines []models.InvoiceItemForReturn
+	for _, returnItem := range returnItems {
+		itemQty, _ := strconv.Atoi(returnItem.Quantity)
+		if itemQty < 1 {
+			err = errors.New("invalid return quantity")
+			return 0, err
+		}
+
+		for i := range invoiceItems {
+			if strconv.Itoa(invoiceItems[i].ItemID) != returnItem.ItemID {
+				continue
+			}
+
+			returnableQty := invoiceItems[i].Qty - invoiceItems[i].ReturnedQty
+			if returnableQty <= 0 {
+				continue
+			}
+
+			returnQty := returnableQty
+			if itemQty < returnableQty {
+				returnQty = itemQty
+			}
+			itemQty = itemQty - returnQty
+			invoiceItems[i].ReturnedQty = invoiceItems[i].ReturnedQty + returnQty
+
+			returnLine := invoiceItems[i]
+			returnLine.Qty = returnQty
+			returnLines = append(returnLines, returnLine)
+
+			if itemQty == 0 {
+				break
+			}
+		}
+
+		if itemQty != 0 {
+			err = fmt.Errorf("return quantity for item %s is higher than the invoiced quantity", returnItem.ItemID)
+			return 0, err
+		}
+	}
+
+	var cashAccountID sql.NullInt32
+	err = tx.QueryRow(queries.OfficerAccNo, form.Get("user_id")).Scan(&cashAccountID)
+	if err != nil {
+		return 0, err
+	}
+
+	if !cashAccountID.Valid {
+		err = errors.New("cash in hand account not specififed")
+		return 0, err
+	}
+
+	irid, err := mysequel.Insert(mysequel.Table{
+		TableName: "invoice_return",
+		Columns:   []string{"invoice_id", "user_id", "cost_price", "price_before_discount", "discount", "price_after_discount", "remarks"},
+		Vals:      []interface{}{iid, form.Get("user_id"), 0, 0, invoiceItems[0].Discount, 0, form.Get("remark")},
+		Tx:        tx,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var costPrice, price, costPriceWithoutLCs float64
+	for _, line := range returnLines {
+		costPrice = costPrice + (line.CostPrice * float64(line.Qty))
+		price = price + (line.Price * float64(line.Qty))
+		costPriceWithoutLCs = costPriceWithoutLCs + (line.CostPriceWithoutLandedCosts * float64(line.Qty))
+
+		err = restoreInvoicedStock(tx, line)
+		if err != nil {
+			return 0, err
+		}
+
+		_, err = mysequel.Insert(mysequel.Table{
+			TableName: "invoice_return_item",
+			Columns:   []string{"invoice_return_id", "invoice_item_id", "entry_specifier", "item_id", "goods_received_note_id", "inventory_transfer_id", "qty", "cost_price", "price"},
+			Vals:      []interface{}{irid, line.InvoiceItemID, line.EntrySpecifier, line.ItemID, line.GoodsReceivedNoteID, nullInt32Value(line.InventoryTransferID), line.Qty, line.CostPrice, line.Price},
+			Tx:        tx,
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	priceAfterDiscount := math.Round((price*(float64(100)-invoiceItems[0].Discount)/100)*100) / 100
+
+	tid, err := createTransaction(tx, form.Get("user_id"), time.Now().Format("2006-01-02"), fmt.Sprintf("INVOICE RETURN %d [INVOICE %d]", irid, iid))
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = mysequel.Update(mysequel.UpdateTable{
+		Table: mysequel.Table{
+			TableName: "invoice_return",
+			Columns:   []string{"cost_price", "price_before_discount", "price_after_discount", "transaction_id"},
+			Vals:      []interface{}{costPrice, price, priceAfterDiscount, tid},
+			Tx:        tx,
+		},
+		WColumns: []string{"id"},
+		WVals:    []string{strconv.FormatInt(irid, 10)},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	salesItems := make([]salesItem, len(returnLines))
+	for i, line := range returnLines {
+		salesItems[i] = salesItem{ItemID: line.ItemID, Price: line.Price * float64(line.Qty), Cost: line.CostPriceWithoutLandedCosts * float64(line.Qty)}
+	}
+
+	postings, err := salesPostings(tx, strconv.Itoa(invoiceItems[0].WarehouseID), salesItems, priceAfterDiscount, invoiceItems[0].Discount)
+	if err != nil {
+		return 0, err
+	}
+
+	journalEntries := []smodels.JournalEntry{
+		{Account: fmt.Sprintf("%d", cashAccountID.Int32), Debit: "", Credit: fmt.Sprintf("%f", priceAfterDiscount)},
+	}
+	for _, posting := range postings {
+		journalEntries = append(journalEntries,
+			smodels.JournalEntry{Account: fmt.Sprintf("%d", posting.SalesAccountID), Debit: fmt.Sprintf("%f", posting.Price), Credit: ""},
+			smodels.JournalEntry{Account: fmt.Sprintf("%d", posting.StockAccountID), Debit: fmt.Sprintf("%f", posting.Cost), Credit: ""},
+			smodels.JournalEntry{Account: fmt.Sprintf("%d", posting.CostOfSalesAccountID), Debit: "", Credit: fmt.Sprintf("%f", posting.Cost)},
+		)
+	}
+	err = scribe.IssueJournalEntries(tx, tid, journalEntries)
+	if err != nil {
+		return 0, err
+	}
+
+	return irid, nil
+}
+
 func requestExists(tx *sql.Tx, requestId string) bool {
 	var requestDBId int
 	err := tx.QueryRow(queries.RequestPresentCheck, requestId).Scan(&requestDBId)
@@ -824,6 +1042,160 @@ func (m *Transactions) InventoryTransferAction(rparams, oparams []string, form u
 	return 0, nil
 }
 
+// VoidInvoice cancels a same day invoice by restoring all the quantities
+// it consumed from the current stock and reversing its journal entries
+func (m *Transactions) VoidInvoice(iid int, form url.Values) (int64, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			m.TransactionsLogger.Printf("VoidInvoice: rolling back invoice %d: %v", iid, err)
+			_ = tx.Rollback()
+			return
+		}
+		_ = tx.Commit()
+	}()
+
+	var sameDay, voided bool
+	var tid sql.NullInt64
+	err = tx.QueryRow(queries.InvoiceForVoid, iid).Scan(&sameDay, &voided, &tid)
+	if err != nil {
+		return 0, err
+	}
+
+	if voided {
+		err = errors.New("invoice is already voided")
+		return 0, err
+	}
+
+	if !sameDay {
+		err = errors.New("only invoices issued today can be voided")
+		return 0, err
+	}
+
+	var returns int
+	err = tx.QueryRow("SELECT COUNT(*) FROM invoice_return WHERE invoice_id = ?", iid).Scan(&returns)
+	if err != nil {
+		return 0, err
+	}
+
+	if returns != 0 {
+		err = errors.New("invoices with returns cannot be voided")
+		return 0, err
+	}
+
+	if !tid.Valid {
+		err = errors.New("transaction for the invoice not found")
+		return 0, err
+	}
+
+	var invoiceItems []models.InvoiceItemForReturn
+	err = mysequel.QueryToStructs(&invoiceItems, tx, queries.InvoiceItemsForReturn, iid, iid)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, item := range invoiceItems {
+		err = restoreInvoicedStock(tx, item)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	vtid, err := reverseTransaction(tx, form.Get("user_id"), tid.Int64, time.Now().Format("2006-01-02"), fmt.Sprintf("VOID INVOICE %d", iid))
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = mysequel.Update(mysequel.UpdateTable{
+		Table: mysequel.Table{
+			TableName: "invoice",
+			Columns:   []string{"voided_by", "voided_on", "void_reason", "void_transaction_id"},
+			Vals:      []interface{}{form.Get("user_id"), time.Now().Format("2006-01-02 15:04:05"), form.Get("reason"), vtid},
+			Tx:        tx,
+		},
+		WColumns: []string{"id"},
+		WVals:    []string{strconv.Itoa(iid)},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return vtid, nil
+}
+
+// restoreInvoicedStock puts an invoiced quantity back into
+// the current stock entry it was originally taken from
+func restoreInvoicedStock(tx *sql.Tx, line models.InvoiceItemForReturn) error {
+	var res sql.Result
+	var err error
+	if line.InventoryTransferID.Valid {
+		res, err = tx.Exec("UPDATE current_stock SET qty = qty + ? WHERE warehouse_id = ? AND item_id = ? AND goods_received_note_id = ? AND inventory_transfer_id = ? AND entry_specifier = ?", line.Qty, line.WarehouseID, line.ItemID, line.GoodsReceivedNoteID, line.InventoryTransferID.Int32, line.EntrySpecifier)
+	} else {
+		res, err = tx.Exec("UPDATE current_stock SET qty = qty + ? WHERE warehouse_id = ? AND item_id = ? AND goods_received_note_id = ? AND inventory_transfer_id IS NULL AND entry_specifier = ?", line.Qty, line.WarehouseID, line.ItemID, line.GoodsReceivedNoteID, line.EntrySpecifier)
+	}
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected != 1 {
+		return fmt.Errorf("current stock entry %s not found", line.EntrySpecifier)
+	}
+
+	return nil
+}
+
+// reverseTransaction creates a new transaction on the posting date that
+// posts the opposite of every account entry of the given transaction
+func reverseTransaction(tx *sql.Tx, userID string, tid int64, postingDate, remark string) (int64, error) {
+	var entries []models.AccountTransactionEntry
+	err := mysequel.QueryToStructs(&entries, tx, queries.AccountTransactionEntries, tid)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(entries) == 0 {
+		return 0, fmt.Errorf("transaction %d has no account entries", tid)
+	}
+
+	rtid, err := createTransaction(tx, userID, postingDate, remark)
+	if err != nil {
+		return 0, err
+	}
+
+	var journalEntries []smodels.JournalEntry
+	for _, entry := range entries {
+		if entry.Type == "DR" {
+			journalEntries = append(journalEntries, smodels.JournalEntry{Account: fmt.Sprintf("%d", entry.AccountID), Debit: "", Credit: fmt.Sprintf("%f", entry.Amount)})
+		} else {
+			journalEntries = append(journalEntries, smodels.JournalEntry{Account: fmt.Sprintf("%d", entry.AccountID), Debit: fmt.Sprintf("%f", entry.Amount), Credit: ""})
+		}
+	}
+
+	err = scribe.IssueJournalEntries(tx, rtid, journalEntries)
+	if err != nil {
+		return 0, err
+	}
+
+	return rtid, nil
+}
+
+// nullInt32Value returns the value of a nullable integer or an
+// empty string which mysequel stores as NULL
+func nullInt32Value(n sql.NullInt32) interface{} {
+	if n.Valid {
+		return n.Int32
+	}
+	return ""
+}
+
 func ConvertArrayToString(arr []interface{}) string {
 	str := ""
 	for i, elem := range arr {
@@ -59,3 +59,14 @@ func (m *UserModel) Get(username, password string) (*models.JWTUser, error) {
 
 	return u, nil
 }
+
+// ID returns the id of the user with the given username
+func (m *UserModel) ID(username string) (int, error) {
+	var id int
+	err := m.DB.QueryRow("SELECT id FROM user WHERE username = ?", username).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, models.ErrNoRecord
+	}
+
+	return id, err
+}
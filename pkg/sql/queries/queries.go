@@ -28,35 +28,78 @@ const SearchItems = `
 	WHERE (? IS NULL OR CONCAT(item_id, foreign_id, name) LIKE ?)
 `
 
+const purchaseOrderStatus = `
+	CASE
+		WHEN POS.reconciled = 0 AND POS.qty - (POS.reconciled + POS.cancelled) <= 0 THEN 'Cancelled'
+		WHEN PO.closed_on IS NOT NULL THEN 'Closed'
+		WHEN POS.qty - (POS.reconciled + POS.cancelled) <= 0 THEN 'Received'
+		WHEN POS.reconciled > 0 THEN 'Partially Received'
+		ELSE 'Open'
+	END`
+
+const purchaseOrderStatusJoin = `
+	LEFT JOIN (
+		SELECT purchase_order_id, SUM(qty) AS qty, SUM(total_reconciled) AS reconciled, SUM(total_cancelled) AS cancelled
+		FROM purchase_order_item
+		GROUP BY purchase_order_id
+	) POS ON POS.purchase_order_id = PO.id`
+
 const PurchaseOrderList = `
-	SELECT PO.id, BP.name, BP2.name, PO.total_price
+	SELECT PO.id, BP.name, BP2.name, PO.total_price, ` + purchaseOrderStatus + ` AS status, PO.approval_status
 	FROM purchase_order PO
 	LEFT JOIN business_partner BP ON BP.id = PO.supplier_id
-	LEFT JOIN business_partner BP2 ON BP2.id = PO.warehouse_id
+	LEFT JOIN business_partner BP2 ON BP2.id = PO.warehouse_id` + purchaseOrderStatusJoin + `
 	ORDER BY PO.id ASC
 `
 
 const PurchaseOrderDetails = `
-	SELECT PO.id, PO.created,  BP.name as supplier, BP2.name as warehouse, PO.price_before_discount, PO.discount_type, PO.discount_amount, PO.total_price, PO.remarks
+	SELECT PO.id, PO.created,  BP.name as supplier, BP2.name as warehouse, PO.price_before_discount, PO.discount_type, PO.discount_amount, PO.total_price, PO.remarks, PO.currency_code, PO.exchange_rate,
+	` + purchaseOrderStatus + ` AS status, U.name AS closed_by, PO.closed_on, PO.close_reason, PO.approval_status, U2.name AS approved_by, PO.approved_on
 	FROM purchase_order PO
 	LEFT JOIN business_partner BP ON BP.id = PO.supplier_id
 	LEFT JOIN business_partner BP2 ON BP2.id = PO.warehouse_id
+	LEFT JOIN user U ON U.id = PO.closed_by
+	LEFT JOIN user U2 ON U2.id = PO.approved_by` + purchaseOrderStatusJoin + `
 	WHERE PO.id = ?
 	ORDER BY PO.id ASC
 `
 
 const PurchaseOrderItemDetails = `
-	SELECT OI.id, I.item_id AS item_id, I.name, OI.unit_price, OI.qty, OI.total_price
+	SELECT OI.id, I.item_id AS item_id, I.name, OI.unit_price, OI.qty, OI.total_price, OI.total_reconciled, OI.total_cancelled
 	FROM purchase_order_item OI
 	LEFT JOIN item I ON I.id = OI.item_id
 	WHERE OI.purchase_order_id = ?
 `
 
-const PurchaseOrderItemCount = `
-	SELECT OI.id, OI.qty, OI.total_reconciled, OI.total_cancelled
+const PurchaseOrderState = `
+	SELECT closed_on, approval_status, COALESCE(total_price, 0) * exchange_rate
+	FROM purchase_order
+	WHERE id = ? FOR UPDATE
+`
+
+const UserPurchaseOrderApprovalLimit = `
+	SELECT purchase_order_limit
+	FROM user_approval_limit
+	WHERE user_id = ?
+`
+
+const UserSupplierInvoiceApprovalLimit = `
+	SELECT supplier_invoice_limit
+	FROM user_approval_limit
+	WHERE user_id = ?
+`
+
+const PurchaseOrderItemsRemaining = `
+	SELECT OI.id, OI.item_id, OI.qty - (OI.total_reconciled + OI.total_cancelled) AS remaining
 	FROM purchase_order_item OI
-	LEFT JOIN item I ON I.id = OI.item_id
-	WHERE OI.item_id = ? AND OI.purchase_order_id = ? FOR UPDATE;
+	WHERE OI.purchase_order_id = ? AND OI.qty - (OI.total_reconciled + OI.total_cancelled) > 0 FOR UPDATE
+`
+
+const PurchaseOrderItemLines = `
+	SELECT OI.id, OI.qty - (OI.total_reconciled + OI.total_cancelled) AS remaining, OI.unit_price
+	FROM purchase_order_item OI
+	WHERE OI.item_id = ? AND OI.purchase_order_id = ?
+	ORDER BY OI.id FOR UPDATE
 `
 
 const GoodsReceivedNoteList = `
@@ -77,7 +120,7 @@ const InventoryTransferList = `
 `
 
 const GoodsReceivedNoteDetails = `
-	SELECT GRN.id, GRN.created,  BP.name as supplier, BP2.name as warehouse, GRN.price_before_discount, GRN.discount_type, GRN.discount_amount, GRN.total_price, GRN.remarks
+	SELECT GRN.id, GRN.created,  BP.name as supplier, BP2.name as warehouse, GRN.price_before_discount, GRN.discount_type, GRN.discount_amount, GRN.total_price, GRN.remarks, GRN.currency_code, GRN.exchange_rate
 	FROM goods_received_note GRN
 	LEFT JOIN business_partner BP ON BP.id = GRN.supplier_id
 	LEFT JOIN business_partner BP2 ON BP2.id = GRN.warehouse_id
@@ -86,7 +129,7 @@ const GoodsReceivedNoteDetails = `
 `
 
 const GrnItemDetails = `
-	SELECT GRNI.id, I.item_id as item_id, I.name, GRNI.unit_price, GRNI.qty, GRNI.total_price
+	SELECT GRNI.id, I.item_id as item_id, I.name, GRNI.unit_price, GRNI.qty, GRNI.total_price, GRNI.qty_variance, GRNI.price_variance, GRNI.variance_status
 	FROM goods_received_note_item GRNI
 	LEFT JOIN item I ON I.id = GRNI.item_id
 	WHERE GRNI.goods_received_note_id = ?
@@ -108,8 +151,39 @@ const InvoiceItemDetails = `
 	GROUP BY II.item_id, II.price
 `
 
+const InvoiceItemsForReturn = `
+	SELECT II.id, II.entry_specifier, INV.warehouse_id, II.item_id, II.goods_received_note_id, II.inventory_transfer_id, II.qty,
+	COALESCE(R.qty, 0) AS returned_qty, CS.cost_price AS cost_price_without_landed_costs, II.cost_price, II.price, INV.discount, INV.voided_on
+	FROM invoice_item II
+	LEFT JOIN invoice INV ON INV.id = II.invoice_id
+	LEFT JOIN current_stock CS ON CS.entry_specifier = II.entry_specifier
+	LEFT JOIN (
+		SELECT IRI.invoice_item_id, SUM(IRI.qty) AS qty
+		FROM invoice_return_item IRI
+		LEFT JOIN invoice_return IR ON IR.id = IRI.invoice_return_id
+		WHERE IR.invoice_id = ?
+		GROUP BY IRI.invoice_item_id
+	) R ON R.invoice_item_id = II.id
+	WHERE II.invoice_id = ?
+	FOR UPDATE
+`
+
+const InvoiceForVoid = `
+	SELECT DATE(INV.created) = CURDATE() AS same_day, INV.voided_on IS NOT NULL AS voided,
+	COALESCE(INV.transaction_id, (SELECT T.id FROM transaction T WHERE T.remark = CONCAT('INVOICE ', INV.id) LIMIT 1)) AS transaction_id
+	FROM invoice INV
+	WHERE INV.id = ?
+	FOR UPDATE
+`
+
+const AccountTransactionEntries = `
+	SELECT AT.account_id, AT.type, AT.amount
+	FROM account_transaction AT
+	WHERE AT.transaction_id = ?
+`
+
 const PurchaseOrderData = `
-	SELECT PO.id,  PO.supplier_id , PO.warehouse_id, PO.discount_type, PO.discount_amount
+	SELECT PO.id,  PO.supplier_id , PO.warehouse_id, PO.discount_type, PO.discount_amount, PO.currency_code, PO.exchange_rate
 	FROM purchase_order PO
 	WHERE PO.id = ?
 	ORDER BY PO.id ASC
@@ -123,13 +197,27 @@ const PurchaseOrderItemData = `
 `
 
 const GrnItemDetailsWithOrderTotal = `
-	SELECT GRNI.id, GRNI.item_id,  GRNI.total_price as total_cost_price,  GRNI.qty, GRN.price_before_discount as total_price, GRN.warehouse_id 
+	SELECT GRNI.id, GRNI.item_id,  GRNI.total_price * GRN.exchange_rate as total_cost_price,  GRNI.qty, GRN.price_before_discount * GRN.exchange_rate as total_price, GRN.warehouse_id, COALESCE(I.weight, 0) AS weight, COALESCE(I.volume, 0) AS volume, GRN.id AS goods_received_note_id
 	FROM goods_received_note_item GRNI
 	LEFT JOIN item I ON I.id = GRNI.item_id
 	LEFT JOIN goods_received_note GRN ON GRN.id= GRNI.goods_received_note_id
 	WHERE GRNI.goods_received_note_id = ?
 `
 
+const LandedCostTypeAccounts = `
+	SELECT expense_account_id, payable_account_id, allocation_basis
+	FROM landed_cost_type
+	WHERE id = ?
+`
+
+const GrnItemRemainingStock = `
+	SELECT item_id, SUM(qty + float_qty) AS qty
+	FROM current_stock
+	WHERE goods_received_note_id = ?
+	GROUP BY item_id
+	FOR UPDATE
+`
+
 const WarehouseStock = `
 	SELECT BP.name AS warehouse_name, I.id, I.item_id, I.foreign_id, I.name AS item_name, SUM(CS.qty) AS quantity, I.price
 	FROM current_stock CS
@@ -167,6 +255,81 @@ const WarehouseItemStockWithDocumentIdsAndPrices = `
 	ORDER BY GRN.created ASC
 `
 
+const LatestWarehouseItemStock = `
+	SELECT CS.entry_specifier, CS.warehouse_id, CS.item_id, CS.goods_received_note_id, CS.inventory_transfer_id, CS.qty, 
+	CS.cost_price AS cost_price_without_landed_costs, CS.price AS cost_price, I.price
+	FROM current_stock CS
+	LEFT JOIN goods_received_note GRN ON GRN.id = CS.goods_received_note_id
+	LEFT JOIN item I ON I.id = CS.item_id
+	WHERE CS.warehouse_id = ? AND CS.item_id = ?
+	ORDER BY GRN.created DESC
+	LIMIT 1
+	FOR UPDATE
+`
+
+const GrnItemUnsoldStock = `
+	SELECT CS.entry_specifier, CS.qty, CS.cost_price
+	FROM current_stock CS
+	WHERE CS.goods_received_note_id = ? AND CS.warehouse_id = ? AND CS.item_id = ? AND CS.qty > 0
+	ORDER BY CS.inventory_transfer_id IS NOT NULL, CS.inventory_transfer_id ASC
+	FOR UPDATE
+`
+
+const GrnItemReturnableQty = `
+	SELECT COALESCE((
+		SELECT SUM(SII.qty)
+		FROM supplier_invoice_item SII
+		LEFT JOIN supplier_invoice SI ON SI.id = SII.supplier_invoice_id
+		WHERE SII.goods_received_note_id = ? AND SII.item_id = ? AND SI.status = 'Posted'
+	), 0) - COALESCE((
+		SELECT SUM(SRI.qty)
+		FROM supplier_return_item SRI
+		LEFT JOIN supplier_return SR ON SR.id = SRI.supplier_return_id
+		WHERE SR.goods_received_note_id = ? AND SRI.item_id = ?
+	), 0)
+`
+
+const StockTakeList = `
+	SELECT ST.id, ST.created, BP.name AS warehouse, U.name AS created_by, ST.status, U2.name AS approved_by, ST.approved_on, ST.stock_adjustment_id
+	FROM stock_take ST
+	LEFT JOIN business_partner BP ON BP.id = ST.warehouse_id
+	LEFT JOIN user U ON U.id = ST.user_id
+	LEFT JOIN user U2 ON U2.id = ST.approved_by
+	ORDER BY ST.id DESC
+`
+
+const StockTakeVariance = `
+	SELECT V.*, V.variance * V.unit_cost AS variance_value
+	FROM (
+		SELECT ITEMS.item_id AS id, I.item_id, I.name AS item_name, COALESCE(STI.snapshot_qty, 0) AS snapshot_qty, COALESCE(OH.qty, 0) AS on_hand_qty, COALESCE(C.counted_qty, 0) AS counted_qty,
+		COALESCE(C.counted_qty, 0) - COALESCE(OH.qty, 0) AS variance,
+		COALESCE(
+			(SELECT SUM(CS.qty * CS.cost_price) / NULLIF(SUM(CS.qty), 0) FROM current_stock CS WHERE CS.warehouse_id = ST.warehouse_id AND CS.item_id = ITEMS.item_id AND CS.qty > 0),
+			(SELECT CS.cost_price FROM current_stock CS LEFT JOIN goods_received_note GRN ON GRN.id = CS.goods_received_note_id WHERE CS.warehouse_id = ST.warehouse_id AND CS.item_id = ITEMS.item_id ORDER BY GRN.created DESC LIMIT 1),
+			0) AS unit_cost
+		FROM (
+			SELECT item_id FROM stock_take_item WHERE stock_take_id = ?
+			UNION
+			SELECT item_id FROM stock_take_count WHERE stock_take_id = ?
+		) ITEMS
+		LEFT JOIN stock_take ST ON ST.id = ?
+		LEFT JOIN item I ON I.id = ITEMS.item_id
+		LEFT JOIN stock_take_item STI ON STI.stock_take_id = ST.id AND STI.item_id = ITEMS.item_id
+		LEFT JOIN (
+			SELECT warehouse_id, item_id, SUM(qty) AS qty
+			FROM current_stock
+			GROUP BY warehouse_id, item_id
+		) OH ON OH.warehouse_id = ST.warehouse_id AND OH.item_id = ITEMS.item_id
+		LEFT JOIN (
+			SELECT item_id, SUM(counted_qty) AS counted_qty
+			FROM stock_take_count
+			WHERE stock_take_id = ?
+			GROUP BY item_id
+		) C ON C.item_id = ITEMS.item_id
+	) V
+	ORDER BY V.item_id
+`
+
 const GetPendingTransfers = `
 	SELECT IT.id, IT.created, FBP.name AS from_warehouse, TBP.name AS to_warehouse
 	FROM inventory_transfer IT
@@ -230,6 +393,11 @@ const GetCashInHand = `
 		SELECT AT.account_id, SUM(CASE WHEN AT.type = "DR" THEN AT.amount ELSE 0 END) AS debit, SUM(CASE WHEN AT.type = "CR" THEN AT.amount ELSE 0 END) AS credit 
 		FROM account_transaction AT
         WHERE AT.account_id = (SELECT account_id FROM user WHERE id = ?)
+		AND AT.transaction_id NOT IN (
+			SELECT transaction_id FROM invoice WHERE voided_on IS NOT NULL AND transaction_id IS NOT NULL
+			UNION
+			SELECT void_transaction_id FROM invoice WHERE voided_on IS NOT NULL AND void_transaction_id IS NOT NULL
+		)
 		GROUP BY AT.account_id
 	) AT ON AT.account_id = A.id
 	WHERE AT.account_id = (SELECT account_id FROM user WHERE id = ?)
@@ -237,7 +405,7 @@ const GetCashInHand = `
 
 const GetSalesCommission = `
 	SELECT ROUND(COALESCE(SUM(price_after_discount-cost_price)*0.025, 0), 2)
-	FROM invoice WHERE YEAR(created) = YEAR(NOW()) AND MONTH(created) = MONTH(NOW()) AND invoice_type_id = 1 AND user_id = ?
+	FROM invoice WHERE YEAR(created) = YEAR(NOW()) AND MONTH(created) = MONTH(NOW()) AND invoice_type_id = 1 AND voided_on IS NULL AND user_id = ?
 `
 
 const InvoiceSearch = `
@@ -245,7 +413,7 @@ const InvoiceSearch = `
 	FROM invoice I
 	LEFT JOIN user U ON U.id = I.user_id
 	LEFT JOIN business_partner BP ON BP.id = I.warehouse_id
-	WHERE (? IS NULL OR I.user_id = ?) AND DATE(I.created) BETWEEN ? AND ?
+	WHERE (? IS NULL OR I.user_id = ?) AND DATE(I.created) BETWEEN ? AND ? AND I.voided_on IS NULL
 `
 
 const BusinessPartnerBalances = `
@@ -255,15 +423,31 @@ const BusinessPartnerBalances = `
 	GROUP BY BPF.business_partner_id
 `
 
-const BusinessPartnerBalanceDetail = `
+const businessPartnerBalanceDetail = `
 	SELECT BP.name AS business_partner_name, T.id AS transaction_id, DATE_FORMAT(T.posting_date, '%Y-%m-%d') AS posting_date, DATE_FORMAT(BPF.effective_date, '%Y-%m-%d') AS effective_date, BPF.type, BPF.amount, T.remark
 	FROM business_partner_financial BPF
 	LEFT JOIN transaction T on BPF.transaction_id = T.id
 	LEFT JOIN business_partner BP on BPF.business_partner_id = BP.id
+`
+
+const BusinessPartnerBalanceDetail = businessPartnerBalanceDetail + `
 	WHERE BPF.business_partner_id = ?
 	ORDER BY BPF.effective_date
 `
 
+const BusinessPartnerStatement = businessPartnerBalanceDetail + `
+	WHERE BPF.business_partner_id = ? AND BPF.effective_date BETWEEN ? AND ?
+	ORDER BY BPF.effective_date, BPF.id
+`
+
+const BusinessPartnerOpeningBalance = `
+	SELECT BP.name, COALESCE(SUM(CASE WHEN BPF.type = 'DR' THEN BPF.amount WHEN BPF.type = 'CR' THEN -BPF.amount END), 0)
+	FROM business_partner BP
+	LEFT JOIN business_partner_financial BPF ON BPF.business_partner_id = BP.id AND BPF.effective_date < ?
+	WHERE BP.id = ?
+	GROUP BY BP.id, BP.name
+`
+
 const ItemStock = `
 	SELECT BP.name AS warehouse, I.item_id, I.name, SUM(CS.qty) AS qty, SUM(CS.float_qty) AS float_qty
 	FROM current_stock CS
@@ -274,6 +458,583 @@ const ItemStock = `
 	HAVING SUM(CS.qty) > 0 OR SUM(CS.float_qty) > 0
 `
 
+const ItemMovements = `
+	SELECT M.warehouse_id, BP.name AS warehouse, DATE_FORMAT(M.date, '%Y-%m-%d %H:%i:%s') AS date, M.document_type, M.document_id, M.qty
+	FROM (
+		SELECT GRN.warehouse_id, GRN.created AS date, 'goods_received_note' AS document_type, GRN.id AS document_id, GRNI.qty
+		FROM goods_received_note_item GRNI
+		LEFT JOIN goods_received_note GRN ON GRN.id = GRNI.goods_received_note_id
+		WHERE GRNI.item_id = ? AND GRN.landed_cost_id IS NOT NULL
+		UNION ALL
+		SELECT IT.from_warehouse_id, IT.created, 'inventory_transfer', IT.id, -ITI.qty
+		FROM inventory_transfer_item ITI
+		LEFT JOIN inventory_transfer IT ON IT.id = ITI.inventory_transfer_id
+		WHERE ITI.item_id = ?
+		UNION ALL
+		SELECT CASE WHEN IT.resolution = 'Rejected' THEN IT.from_warehouse_id ELSE IT.to_warehouse_id END, IT.resolved_on, 'inventory_transfer', IT.id, ITI.qty
+		FROM inventory_transfer_item ITI
+		LEFT JOIN inventory_transfer IT ON IT.id = ITI.inventory_transfer_id
+		WHERE ITI.item_id = ? AND IT.resolution IN ('Approved', 'Provisional', 'Rejected')
+		UNION ALL
+		SELECT INV.warehouse_id, INV.created, 'invoice', INV.id, -II.qty
+		FROM invoice_item II
+		LEFT JOIN invoice INV ON INV.id = II.invoice_id
+		WHERE II.item_id = ?
+		UNION ALL
+		SELECT INV.warehouse_id, INV.voided_on, 'invoice_void', INV.id, II.qty
+		FROM invoice_item II
+		LEFT JOIN invoice INV ON INV.id = II.invoice_id
+		WHERE II.item_id = ? AND INV.voided_on IS NOT NULL
+		UNION ALL
+		SELECT INV.warehouse_id, IR.created, 'invoice_return', IR.id, IRI.qty
+		FROM invoice_return_item IRI
+		LEFT JOIN invoice_return IR ON IR.id = IRI.invoice_return_id
+		LEFT JOIN invoice INV ON INV.id = IR.invoice_id
+		WHERE IRI.item_id = ?
+		UNION ALL
+		SELECT SR.warehouse_id, SR.created, 'supplier_return', SR.id, -SRI.qty
+		FROM supplier_return_item SRI
+		LEFT JOIN supplier_return SR ON SR.id = SRI.supplier_return_id
+		WHERE SRI.item_id = ?
+		UNION ALL
+		SELECT SA.warehouse_id, SA.created, 'stock_adjustment', SA.id, SAI.qty
+		FROM stock_adjustment_item SAI
+		LEFT JOIN stock_adjustment SA ON SA.id = SAI.stock_adjustment_id
+		WHERE SAI.item_id = ?
+	) M
+	LEFT JOIN business_partner BP ON BP.id = M.warehouse_id
+	WHERE (? IS NULL OR M.warehouse_id = ?) AND (? IS NULL OR M.date < DATE_ADD(?, INTERVAL 1 DAY))
+	ORDER BY M.warehouse_id, M.date, M.document_type, M.document_id
+`
+
+const WarehouseName = `
+	SELECT id, name
+	FROM business_partner
+	WHERE id = ?
+`
+
+const RecordStockCostChange = `
+	INSERT INTO current_stock_cost_change (entry_specifier, cost_price, landed_costs, price)
+	SELECT entry_specifier, ?, ?, ?
+	FROM current_stock
+	WHERE goods_received_note_id = ? AND item_id = ?
+`
+
+// InventoryValuation rebuilds the stock of every current stock entry as at the end of a
+// date from the movements made after it. Received stock is included from the date its
+// landed cost was posted and valued at its cost before any later cost changes
+const InventoryValuation = `
+	SELECT L.warehouse_id, BP.name AS warehouse, L.item_id AS id, I.item_id, I.name AS item_name, SUM(L.qty) AS qty,
+	SUM(L.qty * L.cost_price) AS cost_value, SUM(L.qty * L.price) AS landed_cost_value
+	FROM (
+		SELECT CS.warehouse_id, CS.item_id,
+		CS.cost_price - COALESCE((SELECT SUM(CSC.cost_price) FROM current_stock_cost_change CSC WHERE CSC.entry_specifier = CS.entry_specifier AND CSC.created >= DATE_ADD(?, INTERVAL 1 DAY)), 0) AS cost_price,
+		CS.price - COALESCE((SELECT SUM(CSC.price) FROM current_stock_cost_change CSC WHERE CSC.entry_specifier = CS.entry_specifier AND CSC.created >= DATE_ADD(?, INTERVAL 1 DAY)), 0) AS price,
+		CS.qty + CS.float_qty
+		+ COALESCE((SELECT SUM(II.qty) FROM invoice_item II LEFT JOIN invoice INV ON INV.id = II.invoice_id WHERE II.entry_specifier = CS.entry_specifier AND INV.created >= DATE_ADD(?, INTERVAL 1 DAY)), 0)
+		- COALESCE((SELECT SUM(II.qty) FROM invoice_item II LEFT JOIN invoice INV ON INV.id = II.invoice_id WHERE II.entry_specifier = CS.entry_specifier AND INV.voided_on >= DATE_ADD(?, INTERVAL 1 DAY)), 0)
+		- COALESCE((SELECT SUM(IRI.qty) FROM invoice_return_item IRI LEFT JOIN invoice_return IR ON IR.id = IRI.invoice_return_id WHERE IRI.entry_specifier = CS.entry_specifier AND IR.created >= DATE_ADD(?, INTERVAL 1 DAY)), 0)
+		+ COALESCE((SELECT SUM(ITI.qty) FROM inventory_transfer_item ITI LEFT JOIN inventory_transfer TIT ON TIT.id = ITI.inventory_transfer_id WHERE ITI.entry_specifier = CS.entry_specifier AND TIT.resolution IN ('Approved', 'Provisional') AND TIT.resolved_on >= DATE_ADD(?, INTERVAL 1 DAY)), 0)
+		+ COALESCE((SELECT SUM(SRI.qty) FROM supplier_return_item SRI LEFT JOIN supplier_return SR ON SR.id = SRI.supplier_return_id WHERE SRI.entry_specifier = CS.entry_specifier AND SR.created >= DATE_ADD(?, INTERVAL 1 DAY)), 0)
+		- COALESCE((SELECT SUM(SAI.qty) FROM stock_adjustment_item SAI LEFT JOIN stock_adjustment SA ON SA.id = SAI.stock_adjustment_id WHERE SAI.entry_specifier = CS.entry_specifier AND SA.created >= DATE_ADD(?, INTERVAL 1 DAY)), 0) AS qty
+		FROM current_stock CS
+		LEFT JOIN goods_received_note GRN ON GRN.id = CS.goods_received_note_id
+		LEFT JOIN landed_cost LC ON LC.id = GRN.landed_cost_id
+		LEFT JOIN inventory_transfer IT ON IT.id = CS.inventory_transfer_id
+		WHERE (CS.inventory_transfer_id IS NULL AND LC.created < DATE_ADD(?, INTERVAL 1 DAY))
+		OR (CS.inventory_transfer_id IS NOT NULL AND IT.resolved_on < DATE_ADD(?, INTERVAL 1 DAY))
+	) L
+	LEFT JOIN item I ON I.id = L.item_id
+	LEFT JOIN business_partner BP ON BP.id = L.warehouse_id
+	GROUP BY L.warehouse_id, BP.name, L.item_id, I.item_id, I.name
+	HAVING SUM(L.qty) != 0
+	ORDER BY BP.name, I.item_id
+`
+
+const ReorderLevelStatus = `
+	SELECT RL.warehouse_id, BP.name AS warehouse, RL.item_id AS id, I.item_id, I.name AS item_name, RL.min_qty, RL.max_qty,
+	COALESCE(CS.qty, 0) AS on_hand, COALESCE(TR.qty, 0) AS in_transit, COALESCE(OO.qty, 0) AS on_order,
+	COALESCE(LP.supplier_id, 0) AS supplier_id, COALESCE(SBP.name, '') AS supplier, COALESCE(LP.unit_price, 0) AS unit_price
+	FROM item_reorder_level RL
+	LEFT JOIN item I ON I.id = RL.item_id
+	LEFT JOIN business_partner BP ON BP.id = RL.warehouse_id
+	LEFT JOIN (
+		SELECT warehouse_id, item_id, SUM(qty) AS qty
+		FROM current_stock
+		GROUP BY warehouse_id, item_id
+	) CS ON CS.warehouse_id = RL.warehouse_id AND CS.item_id = RL.item_id
+	LEFT JOIN (
+		SELECT IT.to_warehouse_id AS warehouse_id, ITI.item_id, SUM(ITI.qty) AS qty
+		FROM inventory_transfer_item ITI
+		LEFT JOIN inventory_transfer IT ON IT.id = ITI.inventory_transfer_id
+		WHERE IT.resolution IS NULL
+		GROUP BY IT.to_warehouse_id, ITI.item_id
+	) TR ON TR.warehouse_id = RL.warehouse_id AND TR.item_id = RL.item_id
+	LEFT JOIN (
+		SELECT PO.warehouse_id, POI.item_id, SUM(GREATEST(POI.qty - (POI.total_reconciled + POI.total_cancelled), 0)) AS qty
+		FROM purchase_order_item POI
+		LEFT JOIN purchase_order PO ON PO.id = POI.purchase_order_id
+		WHERE PO.approval_status = 'Approved' AND PO.closed_on IS NULL
+		GROUP BY PO.warehouse_id, POI.item_id
+	) OO ON OO.warehouse_id = RL.warehouse_id AND OO.item_id = RL.item_id
+	LEFT JOIN (
+		SELECT POI.item_id, PO.supplier_id, POI.unit_price
+		FROM purchase_order_item POI
+		LEFT JOIN purchase_order PO ON PO.id = POI.purchase_order_id
+		WHERE POI.id = (SELECT MAX(POI2.id) FROM purchase_order_item POI2 WHERE POI2.item_id = POI.item_id)
+	) LP ON LP.item_id = RL.item_id
+	LEFT JOIN business_partner SBP ON SBP.id = LP.supplier_id
+	WHERE (? IS NULL OR RL.warehouse_id = ?)
+	ORDER BY supplier, I.item_id
+`
+
+const SupplierReceiptTolerance = `
+	SELECT qty_tolerance, price_tolerance, action
+	FROM supplier_receipt_tolerance
+	WHERE supplier_id = ?
+`
+
+const GrnOpenVariances = `
+	SELECT GRNI.id, GRN.id AS grn_id, GRN.purchase_order_id, BP.name AS supplier, I.item_id, I.name AS item_name,
+	GRNI.qty, GRNI.open_order_qty, GRNI.qty_variance, GRNI.unit_price, GRNI.ordered_unit_price, GRNI.price_variance, GRN.created
+	FROM goods_received_note_item GRNI
+	LEFT JOIN goods_received_note GRN ON GRN.id = GRNI.goods_received_note_id
+	LEFT JOIN business_partner BP ON BP.id = GRN.supplier_id
+	LEFT JOIN item I ON I.id = GRNI.item_id
+	WHERE GRNI.variance_status = 'Open'
+	ORDER BY GRN.id, GRNI.id
+`
+
+const SupplierInvoiceGrnItem = `
+	SELECT GRN.supplier_id, COALESCE(GRN.landed_cost_id, 0), GRN.currency_code, GRN.exchange_rate, GRNI.qty, GRNI.unit_price, COALESCE(POI.unit_price, GRNI.unit_price),
+	COALESCE((
+		SELECT SUM(SII.qty)
+		FROM supplier_invoice_item SII
+		LEFT JOIN supplier_invoice SI ON SI.id = SII.supplier_invoice_id
+		WHERE SII.goods_received_note_id = GRN.id AND SII.item_id = GRNI.item_id AND SI.status <> 'Rejected'
+	), 0)
+	FROM goods_received_note_item GRNI
+	LEFT JOIN goods_received_note GRN ON GRN.id = GRNI.goods_received_note_id
+	LEFT JOIN purchase_order_item POI ON POI.purchase_order_id = GRN.purchase_order_id AND POI.item_id = GRNI.item_id
+	WHERE GRNI.goods_received_note_id = ? AND GRNI.item_id = ?
+	FOR UPDATE
+`
+
+const SupplierInvoiceForPosting = `
+	SELECT supplier_id, invoice_number, DATE_FORMAT(invoice_date, '%Y-%m-%d'), total_amount, grn_value, status, currency_code, exchange_rate
+	FROM supplier_invoice
+	WHERE id = ? FOR UPDATE
+`
+
+const SupplierInvoiceForApproval = `
+	SELECT user_id, status, total_amount * exchange_rate
+	FROM supplier_invoice
+	WHERE id = ? FOR UPDATE
+`
+
+const SupplierInvoiceVarianceLines = `
+	SELECT SII.goods_received_note_id, SII.item_id, COALESCE(I.item_category_id, '') AS item_category_id, GRN.warehouse_id,
+	SII.qty * SII.unit_price AS invoice_amount, SII.qty * SII.grn_unit_price * GRN.exchange_rate AS grn_value,
+	GRNI.qty AS received_qty, COALESCE(CS.qty, 0) AS remaining_qty
+	FROM supplier_invoice_item SII
+	LEFT JOIN goods_received_note GRN ON GRN.id = SII.goods_received_note_id
+	LEFT JOIN goods_received_note_item GRNI ON GRNI.goods_received_note_id = SII.goods_received_note_id AND GRNI.item_id = SII.item_id
+	LEFT JOIN item I ON I.id = SII.item_id
+	LEFT JOIN (
+		SELECT goods_received_note_id, item_id, SUM(qty + float_qty) AS qty
+		FROM current_stock
+		GROUP BY goods_received_note_id, item_id
+	) CS ON CS.goods_received_note_id = SII.goods_received_note_id AND CS.item_id = SII.item_id
+	WHERE SII.supplier_invoice_id = ?
+	ORDER BY SII.id
+`
+
+const SupplierInvoiceList = `
+	SELECT SI.id, BP.name AS supplier, SI.invoice_number, DATE_FORMAT(SI.invoice_date, '%Y-%m-%d') AS invoice_date, DATE_FORMAT(SI.due_date, '%Y-%m-%d') AS due_date, SI.total_amount, SI.grn_value, SI.status
+	FROM supplier_invoice SI
+	LEFT JOIN business_partner BP ON BP.id = SI.supplier_id
+	ORDER BY SI.id DESC
+`
+
+const SupplierInvoiceDetails = `
+	SELECT SI.id, BP.name AS supplier, SI.invoice_number, DATE_FORMAT(SI.invoice_date, '%Y-%m-%d') AS invoice_date, DATE_FORMAT(SI.due_date, '%Y-%m-%d') AS due_date, SI.total_amount, SI.grn_value, SI.status
+	FROM supplier_invoice SI
+	LEFT JOIN business_partner BP ON BP.id = SI.supplier_id
+	WHERE SI.id = ?
+`
+
+const SupplierInvoiceItems = `
+	SELECT SII.id, SII.goods_received_note_id, I.item_id, I.name AS item_name, SII.qty, SII.unit_price, SII.total_price,
+	SII.grn_unit_price, SII.order_unit_price, SII.qty_variance, SII.price_variance, SII.match_status
+	FROM supplier_invoice_item SII
+	LEFT JOIN item I ON I.id = SII.item_id
+	WHERE SII.supplier_invoice_id = ?
+`
+
+const BusinessPartnerForeignCarrying = `
+	SELECT COALESCE(SUM(CASE WHEN type = 'DR' THEN amount ELSE -amount END), 0) AS base_balance,
+	COALESCE(SUM(CASE WHEN type = 'DR' THEN COALESCE(foreign_amount, amount) ELSE -COALESCE(foreign_amount, amount) END), 0) AS foreign_balance
+	FROM business_partner_financial
+	WHERE business_partner_id = ? AND currency_code = ?
+`
+
+const BusinessPartnerForeignBalances = `
+	SELECT BP.id, BP.name AS business_partner, BPF.currency_code,
+	SUM(CASE WHEN BPF.type = 'DR' THEN COALESCE(BPF.foreign_amount, BPF.amount) ELSE -COALESCE(BPF.foreign_amount, BPF.amount) END) AS foreign_balance,
+	SUM(CASE WHEN BPF.type = 'DR' THEN BPF.amount ELSE -BPF.amount END) AS base_balance
+	FROM business_partner_financial BPF
+	LEFT JOIN business_partner BP ON BP.id = BPF.business_partner_id
+	WHERE BPF.currency_code != ? AND BPF.effective_date <= ?
+	GROUP BY BP.id, BP.name, BPF.currency_code
+	HAVING foreign_balance != 0
+	ORDER BY BP.name, BPF.currency_code
+`
+
+const ExchangeRateOn = `
+	SELECT rate
+	FROM exchange_rate
+	WHERE currency_code = ? AND rate_date <= ?
+	ORDER BY rate_date DESC
+	LIMIT 1
+`
+
+const ExchangeRateList = `
+	SELECT ER.id, ER.currency_code, DATE_FORMAT(ER.rate_date, '%Y-%m-%d') AS rate_date, ER.rate, U.name AS entered_by
+	FROM exchange_rate ER
+	LEFT JOIN user U ON U.id = ER.user_id
+	WHERE (? IS NULL OR ER.currency_code = ?)
+	ORDER BY ER.rate_date DESC, ER.currency_code
+`
+
+// supplierOpenItems lists goods received notes with posted supplier invoices and their
+// amounts in the document currency. Returns are stored in the base currency
+const supplierOpenItems = `
+	SELECT GRN.id AS grn_id, DATE_FORMAT(GRN.effective_date, '%Y-%m-%d') AS effective_date, GRN.currency_code, INV.invoice_numbers,
+	INV.amount AS invoiced, ROUND(COALESCE(RET.amount, 0) / GRN.exchange_rate, 2) AS returned, COALESCE(AL.amount, 0) AS allocated,
+	INV.amount - ROUND(COALESCE(RET.amount, 0) / GRN.exchange_rate, 2) - COALESCE(AL.amount, 0) AS open_amount
+	FROM goods_received_note GRN
+	INNER JOIN (
+		SELECT SII.goods_received_note_id, GROUP_CONCAT(DISTINCT SI.invoice_number) AS invoice_numbers, SUM(SII.total_price) AS amount
+		FROM supplier_invoice_item SII
+		INNER JOIN supplier_invoice SI ON SI.id = SII.supplier_invoice_id AND SI.status = 'Posted'
+		GROUP BY SII.goods_received_note_id
+	) INV ON INV.goods_received_note_id = GRN.id
+	LEFT JOIN (
+		SELECT goods_received_note_id, SUM(total_price) AS amount
+		FROM supplier_return
+		GROUP BY goods_received_note_id
+	) RET ON RET.goods_received_note_id = GRN.id
+	LEFT JOIN (
+		SELECT goods_received_note_id, SUM(amount) AS amount
+		FROM business_partner_payment_allocation
+		WHERE goods_received_note_id IS NOT NULL
+		GROUP BY goods_received_note_id
+	) AL ON AL.goods_received_note_id = GRN.id
+`
+
+const SupplierOpenItems = supplierOpenItems + `
+	WHERE GRN.supplier_id = ?
+	HAVING open_amount > 0.005
+	ORDER BY GRN.effective_date, GRN.id
+`
+
+const SupplierOpenItem = supplierOpenItems + `
+	WHERE GRN.supplier_id = ? AND GRN.id = ?
+`
+
+const SupplierOnAccount = `
+	SELECT currency_code, SUM(amount) AS amount
+	FROM business_partner_payment_allocation
+	WHERE business_partner_id = ? AND goods_received_note_id IS NULL
+	GROUP BY currency_code
+	HAVING amount > 0.005
+	ORDER BY currency_code
+`
+
+const SupplierOnAccountBalance = `
+	SELECT COALESCE(SUM(amount), 0)
+	FROM business_partner_payment_allocation
+	WHERE business_partner_id = ? AND currency_code = ? AND goods_received_note_id IS NULL
+	FOR UPDATE
+`
+
+// BusinessPartnerAgingItems lists the goods received notes open as at a date by the
+// supplier invoices, returns and payment allocations made up to the date
+const BusinessPartnerAgingItems = `
+	SELECT GRN.supplier_id AS business_partner_id, BP.name AS business_partner, GRN.id AS grn_id, 0 AS transaction_id,
+	DATE_FORMAT(GRN.effective_date, '%Y-%m-%d') AS effective_date, GRN.currency_code, INV.base_amount / INV.amount AS exchange_rate,
+	-(INV.amount - ROUND(COALESCE(RET.amount, 0) / GRN.exchange_rate, 2) - COALESCE(AL.amount, 0)) AS amount, INV.invoice_numbers AS remark
+	FROM goods_received_note GRN
+	INNER JOIN (
+		SELECT SII.goods_received_note_id, GROUP_CONCAT(DISTINCT SI.invoice_number) AS invoice_numbers,
+		SUM(SII.total_price) AS amount, SUM(SII.total_price * SI.exchange_rate) AS base_amount
+		FROM supplier_invoice_item SII
+		INNER JOIN supplier_invoice SI ON SI.id = SII.supplier_invoice_id AND SI.status = 'Posted'
+		WHERE SI.invoice_date <= ?
+		GROUP BY SII.goods_received_note_id
+	) INV ON INV.goods_received_note_id = GRN.id
+	LEFT JOIN (
+		SELECT goods_received_note_id, SUM(total_price) AS amount
+		FROM supplier_return
+		WHERE DATE(created) <= ?
+		GROUP BY goods_received_note_id
+	) RET ON RET.goods_received_note_id = GRN.id
+	LEFT JOIN (
+		SELECT BPPA.goods_received_note_id, SUM(BPPA.amount) AS amount
+		FROM business_partner_payment_allocation BPPA
+		LEFT JOIN transaction T ON T.id = BPPA.transaction_id
+		WHERE BPPA.goods_received_note_id IS NOT NULL AND COALESCE(T.posting_date, DATE(BPPA.created)) <= ?
+		GROUP BY BPPA.goods_received_note_id
+	) AL ON AL.goods_received_note_id = GRN.id
+	LEFT JOIN business_partner BP ON BP.id = GRN.supplier_id
+	WHERE ? IS NULL OR GRN.supplier_id = ?
+	HAVING amount < -0.005
+`
+
+// BusinessPartnerAgingOnAccount lists the amounts paid on account and allocated from
+// account up to a date in the order they were made
+const BusinessPartnerAgingOnAccount = `
+	SELECT BPPA.business_partner_id, BP.name AS business_partner, 0 AS grn_id, COALESCE(BPPA.transaction_id, 0) AS transaction_id,
+	DATE_FORMAT(COALESCE(T.posting_date, BPPA.created), '%Y-%m-%d') AS effective_date, BPPA.currency_code,
+	COALESCE((
+		SELECT BPF.exchange_rate
+		FROM business_partner_financial BPF
+		WHERE BPF.transaction_id = BPPA.transaction_id AND BPF.business_partner_id = BPPA.business_partner_id
+		LIMIT 1
+	), 1) AS exchange_rate,
+	BPPA.amount, COALESCE(T.remark, '') AS remark
+	FROM business_partner_payment_allocation BPPA
+	LEFT JOIN transaction T ON T.id = BPPA.transaction_id
+	LEFT JOIN business_partner BP ON BP.id = BPPA.business_partner_id
+	WHERE BPPA.goods_received_note_id IS NULL AND COALESCE(T.posting_date, DATE(BPPA.created)) <= ? AND (? IS NULL OR BPPA.business_partner_id = ?)
+	ORDER BY BPPA.business_partner_id, BPPA.currency_code, COALESCE(T.posting_date, DATE(BPPA.created)), BPPA.id
+`
+
+const ChequeForUpdate = `
+	SELECT transaction_id, cheque_number, status
+	FROM cheque
+	WHERE id = ? FOR UPDATE
+`
+
+// cheques lists the cheque register with the business partners paid by each cheque
+const cheques = `
+	SELECT C.id, C.cheque_number, A.name AS account, COALESCE(P.payee, '') AS payee, DATE_FORMAT(C.issue_date, '%Y-%m-%d') AS issue_date,
+	DATE_FORMAT(C.effective_date, '%Y-%m-%d') AS effective_date, C.amount, C.status, C.transaction_id
+	FROM cheque C
+	LEFT JOIN account A ON A.id = C.account_id
+	LEFT JOIN (
+		SELECT BPF.transaction_id, GROUP_CONCAT(DISTINCT BP.name SEPARATOR ', ') AS payee
+		FROM business_partner_financial BPF
+		LEFT JOIN business_partner BP ON BP.id = BPF.business_partner_id
+		GROUP BY BPF.transaction_id
+	) P ON P.transaction_id = C.transaction_id
+`
+
+const ChequeList = cheques + `
+	WHERE (? IS NULL OR C.status = ?)
+	ORDER BY C.issue_date DESC, C.id DESC
+`
+
+const ChequesMaturing = cheques + `
+	WHERE C.status IN ('Issued', 'Presented') AND C.effective_date BETWEEN ? AND ?
+	ORDER BY C.effective_date, C.id
+`
+
+const BusinessPartnerFinancialByTransaction = `
+	SELECT business_partner_id, type, amount, currency_code, COALESCE(foreign_amount, amount), exchange_rate
+	FROM business_partner_financial
+	WHERE transaction_id = ?
+`
+
+const PaymentOnAccount = `
+	SELECT business_partner_id, currency_code, amount
+	FROM business_partner_payment_allocation
+	WHERE transaction_id = ? AND goods_received_note_id IS NULL AND amount > 0
+`
+
+const ReversePaymentAllocations = `
+	INSERT INTO business_partner_payment_allocation (user_id, transaction_id, business_partner_id, goods_received_note_id, currency_code, amount)
+	SELECT ?, ?, business_partner_id, goods_received_note_id, currency_code, -amount
+	FROM business_partner_payment_allocation
+	WHERE transaction_id = ?
+`
+
+const bankStatementLines = `
+	SELECT BSL.id, DATE_FORMAT(BSL.transaction_date, '%Y-%m-%d') AS transaction_date, COALESCE(BSL.description, '') AS description,
+	COALESCE(BSL.reference, '') AS reference, BSL.amount, COALESCE(BSL.account_transaction_id, 0) AS account_transaction_id,
+	COALESCE(AT.transaction_id, 0) AS transaction_id, COALESCE(BSL.match_type, '') AS match_type
+	FROM bank_statement_line BSL
+	LEFT JOIN account_transaction AT ON AT.id = BSL.account_transaction_id
+`
+
+const BankStatementLines = bankStatementLines + `
+	WHERE BSL.bank_statement_id = ?
+	ORDER BY BSL.transaction_date, BSL.id
+`
+
+const BankUnmatchedStatementLines = bankStatementLines + `
+	WHERE BSL.account_id = ? AND BSL.transaction_date <= ? AND BSL.account_transaction_id IS NULL
+	ORDER BY BSL.transaction_date, BSL.id
+`
+
+const BankStatementLineForUpdate = `
+	SELECT account_id, amount, account_transaction_id
+	FROM bank_statement_line
+	WHERE id = ? FOR UPDATE
+`
+
+const BankAccountEntry = `
+	SELECT AT.account_id, CASE WHEN AT.type = 'DR' THEN AT.amount ELSE -AT.amount END, BSL.id
+	FROM account_transaction AT
+	LEFT JOIN bank_statement_line BSL ON BSL.account_transaction_id = AT.id
+	WHERE AT.id = ?
+`
+
+const BankMatchCandidate = `
+	SELECT AT.id
+	FROM account_transaction AT
+	LEFT JOIN transaction T ON T.id = AT.transaction_id
+	LEFT JOIN cheque C ON C.transaction_id = AT.transaction_id
+	LEFT JOIN bank_statement_line BSL ON BSL.account_transaction_id = AT.id
+	WHERE AT.account_id = ? AND AT.type = ? AND AT.amount = ROUND(?, 2) AND BSL.id IS NULL
+	AND (TRIM(LEADING '0' FROM C.cheque_number) = TRIM(LEADING '0' FROM ?) OR T.posting_date BETWEEN DATE_SUB(?, INTERVAL ? DAY) AND DATE_ADD(?, INTERVAL ? DAY))
+	ORDER BY TRIM(LEADING '0' FROM C.cheque_number) = TRIM(LEADING '0' FROM ?) DESC, ABS(DATEDIFF(T.posting_date, ?)), AT.id
+	LIMIT 1
+`
+
+const BankReconciliationBalances = `
+	SELECT (
+		SELECT COALESCE(SUM(CASE WHEN AT.type = 'DR' THEN AT.amount ELSE -AT.amount END), 0)
+		FROM account_transaction AT
+		LEFT JOIN transaction T ON T.id = AT.transaction_id
+		WHERE AT.account_id = ? AND T.posting_date <= ?
+	) AS book_balance, (
+		SELECT closing_balance
+		FROM bank_statement
+		WHERE account_id = ? AND statement_date <= ?
+		ORDER BY statement_date DESC, id DESC
+		LIMIT 1
+	) AS statement_balance
+`
+
+// BankUnmatchedEntries excludes cancelled cheques and their reversals which never reach the bank
+const BankUnmatchedEntries = `
+	SELECT AT.id, T.id AS transaction_id, DATE_FORMAT(T.posting_date, '%Y-%m-%d') AS posting_date, COALESCE(C.cheque_number, '') AS cheque_number,
+	COALESCE(T.remark, '') AS remark, AT.amount
+	FROM account_transaction AT
+	LEFT JOIN transaction T ON T.id = AT.transaction_id
+	LEFT JOIN cheque C ON C.transaction_id = AT.transaction_id
+	LEFT JOIN bank_statement_line BSL ON BSL.account_transaction_id = AT.id
+	WHERE AT.account_id = ? AND T.posting_date <= ? AND AT.type = ? AND BSL.id IS NULL
+	AND NOT EXISTS (SELECT 1 FROM cheque CC WHERE CC.status = 'Cancelled' AND (CC.transaction_id = AT.transaction_id OR CC.reversal_transaction_id = AT.transaction_id))
+	ORDER BY T.posting_date, AT.id
+`
+
+const PostingRuleAccount = `
+	SELECT account_id
+	FROM posting_rule
+	WHERE rule = ? AND (item_category_id IS NULL OR item_category_id = ?) AND (warehouse_id IS NULL OR warehouse_id = ?)
+	ORDER BY item_category_id IS NULL, warehouse_id IS NULL
+	LIMIT 1
+`
+
+const PostingRuleList = `
+	SELECT PR.id, PR.rule, COALESCE(PR.item_category_id, 0) AS item_category_id, COALESCE(IC.name, '') AS item_category,
+	COALESCE(PR.warehouse_id, 0) AS warehouse_id, COALESCE(W.name, '') AS warehouse, PR.account_id, COALESCE(A.name, '') AS account
+	FROM posting_rule PR
+	LEFT JOIN item_category IC ON IC.id = PR.item_category_id
+	LEFT JOIN business_partner W ON W.id = PR.warehouse_id
+	LEFT JOIN account A ON A.id = PR.account_id
+	ORDER BY PR.rule, PR.item_category_id, PR.warehouse_id
+`
+
+const PostingRulesWithoutAccount = `
+	SELECT COUNT(*)
+	FROM posting_rule PR
+	LEFT JOIN account A ON A.id = PR.account_id
+	WHERE A.id IS NULL
+`
+
+const PostingRulesTooSpecific = `
+	SELECT COUNT(*)
+	FROM posting_rule
+	WHERE (rule = ? AND warehouse_id IS NOT NULL) OR (rule = ? AND (item_category_id IS NOT NULL OR warehouse_id IS NOT NULL))
+`
+
+const PostingRuleAccounts = `
+	SELECT DISTINCT account_id
+	FROM posting_rule
+	WHERE rule = ?
+`
+
+const ItemCategoryOfItem = `
+	SELECT COALESCE(item_category_id, '') FROM item WHERE id = ?
+`
+
+const FinancialYearOverlap = `
+	SELECT COUNT(*) FROM financial_year WHERE start_date <= ? AND end_date >= ?
+`
+
+const FinancialYears = `
+	SELECT id, name, DATE_FORMAT(start_date, '%Y-%m-%d') AS start_date, DATE_FORMAT(end_date, '%Y-%m-%d') AS end_date, status
+	FROM financial_year
+	ORDER BY start_date DESC
+`
+
+const FinancialPeriods = `
+	SELECT FP.id, FP.name, DATE_FORMAT(FP.start_date, '%Y-%m-%d') AS start_date, DATE_FORMAT(FP.end_date, '%Y-%m-%d') AS end_date, FP.status,
+	COALESCE(U.name, '') AS status_changed_by, COALESCE(DATE_FORMAT(FP.status_changed_on, '%Y-%m-%d %H:%i:%s'), '') AS status_changed_on
+	FROM financial_period FP
+	LEFT JOIN user U ON U.id = FP.status_changed_by
+	WHERE FP.financial_year_id = ?
+	ORDER BY FP.start_date
+`
+
+const FinancialPeriodStatus = `
+	SELECT status FROM financial_period WHERE ? BETWEEN start_date AND end_date LOCK IN SHARE MODE
+`
+
+const SetFinancialPeriodStatus = `
+	UPDATE financial_period FP
+	INNER JOIN financial_year FY ON FY.id = FP.financial_year_id AND FY.status = 'Open'
+	SET FP.status = ?, FP.status_changed_by = ?, FP.status_changed_on = NOW()
+	WHERE FP.id = ?
+`
+
+const UserPeriodOverride = `
+	SELECT period_override FROM user WHERE id = ?
+`
+
+const financialYear = `
+	SELECT name, DATE_FORMAT(start_date, '%Y-%m-%d') AS start_date, DATE_FORMAT(end_date, '%Y-%m-%d') AS end_date, status
+	FROM financial_year
+	WHERE id = ?
+`
+
+const FinancialYear = financialYear
+
+const FinancialYearForUpdate = financialYear + `FOR UPDATE`
+
+const SetFinancialYearPeriodsStatus = `
+	UPDATE financial_period
+	SET status = ?, status_changed_by = ?, status_changed_on = NOW()
+	WHERE financial_year_id = ?
+`
+
+const OpenFinancialYearClosing = `
+	SELECT id, COALESCE(transaction_id, 0) FROM financial_year_closing
+	WHERE financial_year_id = ? AND reopened_on IS NULL
+	ORDER BY id DESC
+	LIMIT 1
+`
+
+const FinancialPeriodYearClosings = `
+	SELECT COALESCE(SUM(FYC.reopened_on IS NULL), 0) AS closed, COALESCE(SUM(FYC.reopened_on IS NOT NULL), 0) AS reopened
+	FROM financial_period FP
+	INNER JOIN financial_year_closing FYC ON FYC.financial_year_id = FP.financial_year_id
+	WHERE FP.id = ?
+`
+
 const RequestPresentCheck = `
 	SELECT UR.id
 	FROM unique_requests UR
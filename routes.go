@@ -18,6 +18,7 @@ func (app *application) routes() http.Handler {
 	r.Handle("/dropdown/condition/{name}/{where}/{value}", app.validateToken(http.HandlerFunc(app.dropdownConditionHandler))).Methods("GET")
 	r.Handle("/dropdown/condition/accounts/{name}/{where}/{value}", app.validateToken(http.HandlerFunc(app.dropdownConditionAccountsHandler))).Methods("GET")
 	r.Handle("/dropdown/custom/grn", app.validateToken(http.HandlerFunc(app.dropdownGrnHandler))).Methods("GET")
+	r.Handle("/dropdown/custom/grn/costed", app.validateToken(http.HandlerFunc(app.dropdownCostedGrnHandler))).Methods("GET")
 	r.Handle("/dropdown/custom/items", app.validateToken(http.HandlerFunc(app.dropdownItemsHandler))).Methods("GET")
 	r.Handle("/dropdown/multicondition/{name}/{where}/{value}/{operator}", app.validateToken(http.HandlerFunc(app.dropdownMultiConditionHandler))).Methods("GET")
 
@@ -27,14 +28,44 @@ func (app *application) routes() http.Handler {
 	r.Handle("/item/{id}", app.validateToken(http.HandlerFunc(app.itemDetails))).Methods("GET")
 	r.Handle("/item/details/byid/{id}", app.validateToken(http.HandlerFunc(app.itemDetailsById))).Methods("GET")
 	r.Handle("/item/update/byid", app.validateToken(http.HandlerFunc(app.updateItemById))).Methods("POST")
+	r.Handle("/item/reorderlevel", app.validateToken(http.HandlerFunc(app.setItemReorderLevel))).Methods("POST")
 	fileServer := http.FileServer(http.Dir("./ui/static/"))
 	r.Handle("/item/stock/{id}", app.validateToken(http.HandlerFunc(app.itemStock))).Methods("GET")
+	r.Handle("/item/{id}/movements", app.validateToken(http.HandlerFunc(app.itemMovements))).Methods("GET")
 
 	r.Handle("/businesspartner/create", app.validateToken(http.HandlerFunc(app.createBusinessPartner))).Methods("POST")
+	r.Handle("/businesspartner/receipttolerance", app.validateToken(http.HandlerFunc(app.setReceiptTolerance))).Methods("POST")
+	r.Handle("/cheque/status", app.validateToken(http.HandlerFunc(app.updateChequeStatus))).Methods("POST")
+	r.Handle("/cheque/list", app.validateToken(http.HandlerFunc(app.chequeList))).Methods("GET")
+	r.Handle("/cheque/maturing", app.validateToken(http.HandlerFunc(app.chequesMaturing))).Methods("GET")
+	r.Handle("/bankreconciliation/import", app.validateToken(http.HandlerFunc(app.importBankStatement))).Methods("POST")
+	r.Handle("/bankreconciliation/statement/{sid}", app.validateToken(http.HandlerFunc(app.bankStatement))).Methods("GET")
+	r.Handle("/bankreconciliation/match", app.validateToken(http.HandlerFunc(app.matchBankStatementLine))).Methods("POST")
+	r.Handle("/bankreconciliation/unmatch", app.validateToken(http.HandlerFunc(app.unmatchBankStatementLine))).Methods("POST")
+	r.Handle("/bankreconciliation/report", app.validateToken(http.HandlerFunc(app.bankReconciliation))).Methods("GET")
+	r.Handle("/exchangerate/new", app.validateToken(http.HandlerFunc(app.createExchangeRate))).Methods("POST")
+	r.Handle("/exchangerate/import", app.validateToken(http.HandlerFunc(app.importExchangeRates))).Methods("POST")
+	r.Handle("/exchangerate/list", app.validateToken(http.HandlerFunc(app.exchangeRateList))).Methods("GET")
+	r.Handle("/exchangerate/revaluation", app.validateToken(http.HandlerFunc(app.exchangeRevaluation))).Methods("POST")
+	r.Handle("/businesspartner/foreignbalances", app.validateToken(http.HandlerFunc(app.businessPartnerForeignBalances))).Methods("GET")
 	r.Handle("/businesspartner/balances", app.validateToken(http.HandlerFunc(app.businessPartnerBalances))).Methods("GET")
+	r.Handle("/businesspartner/aging", app.validateToken(http.HandlerFunc(app.businessPartnerAging))).Methods("GET")
+	r.Handle("/businesspartner/aging/{bpid}", app.validateToken(http.HandlerFunc(app.businessPartnerAgingDetail))).Methods("GET")
 	r.Handle("/businesspartner/payment", app.validateToken(http.HandlerFunc(app.businessPartnerPayment))).Methods("POST")
+	r.Handle("/businesspartner/payment/allocate", app.validateToken(http.HandlerFunc(app.allocateBusinessPartnerPayment))).Methods("POST")
 	r.Handle("/businesspartner/balance/{bpid}", app.validateToken(http.HandlerFunc(app.bpBalanceDetail))).Methods("GET")
-
+	r.Handle("/businesspartner/openitems/{bpid}", app.validateToken(http.HandlerFunc(app.bpOpenItems))).Methods("GET")
+	r.Handle("/businesspartner/statement/{bpid}", app.validateToken(http.HandlerFunc(app.bpStatement))).Methods("GET")
+
+	r.Handle("/account/financialyear/new", app.validateToken(http.HandlerFunc(app.createFinancialYear))).Methods("POST")
+	r.Handle("/account/financialyear/list", app.validateToken(http.HandlerFunc(app.financialYears))).Methods("GET")
+	r.Handle("/account/financialyear/{fyid}/periods", app.validateToken(http.HandlerFunc(app.financialPeriods))).Methods("GET")
+	r.Handle("/account/financialyear/{fyid}/closing", app.validateToken(http.HandlerFunc(app.financialYearClosingPreview))).Methods("GET")
+	r.Handle("/account/financialyear/close", app.validateToken(http.HandlerFunc(app.closeFinancialYear))).Methods("POST")
+	r.Handle("/account/financialyear/reopen", app.validateToken(http.HandlerFunc(app.reopenFinancialYear))).Methods("POST")
+	r.Handle("/account/financialperiod/status", app.validateToken(http.HandlerFunc(app.setFinancialPeriodStatus))).Methods("POST")
+	r.Handle("/account/postingrule", app.validateToken(http.HandlerFunc(app.setPostingRule))).Methods("POST")
+	r.Handle("/account/postingrule/list", app.validateToken(http.HandlerFunc(app.postingRuleList))).Methods("GET")
 	r.Handle("/account/category/new", app.validateToken(http.HandlerFunc(app.newAccountCategory))).Methods("POST")
 	r.Handle("/account/new", app.validateToken(http.HandlerFunc(app.newAccount))).Methods("POST")
 	r.Handle("/account/deposit", app.validateToken(http.HandlerFunc(app.accountDeposit))).Methods("POST")
@@ -56,15 +87,30 @@ func (app *application) routes() http.Handler {
 	r.Handle("/account/commission/{uid}", app.validateToken(http.HandlerFunc(app.salesCommission))).Methods("GET")
 
 	r.Handle("/transaction/purchaseorder/new", app.validateToken(http.HandlerFunc(app.createOrder))).Methods("POST")
+	r.Handle("/transaction/purchaseorder/cancelitem", app.validateToken(http.HandlerFunc(app.cancelOrderItem))).Methods("POST")
+	r.Handle("/transaction/purchaseorder/approve", app.validateToken(http.HandlerFunc(app.approveOrder))).Methods("POST")
+	r.Handle("/transaction/purchaseorder/close", app.validateToken(http.HandlerFunc(app.closeOrder))).Methods("POST")
 	r.Handle("/transaction/purchaseorder/list", app.validateToken(http.HandlerFunc(app.purchaseOrderList))).Methods("GET")
 	r.Handle("/transaction/purchaseorder/{pid}", app.validateToken(http.HandlerFunc(app.purchaseOrderDetails))).Methods("GET")
 
 	r.Handle("/transaction/goodsreceivednote/new", app.validateToken(http.HandlerFunc(app.createGoodsReceivedNote))).Methods("POST")
 	r.Handle("/transaction/goodsreceivednote/list", app.validateToken(http.HandlerFunc(app.goodsReceivedNoteList))).Methods("GET")
+	r.Handle("/transaction/goodsreceivednote/variances", app.validateToken(http.HandlerFunc(app.goodsReceivedNoteVariances))).Methods("GET")
+	r.Handle("/transaction/goodsreceivednote/variance/resolve", app.validateToken(http.HandlerFunc(app.resolveGoodsReceivedNoteVariance))).Methods("POST")
 	r.Handle("/transaction/goodsreceivednote/{grnid}", app.validateToken(http.HandlerFunc(app.goodsReceivedNoteDetails))).Methods("GET")
 	r.Handle("/transaction/copypurchaseorder/{pid}", app.validateToken(http.HandlerFunc(app.purchaseOrderData))).Methods("GET")
 
 	r.Handle("/transaction/landedcost/new", app.validateToken(http.HandlerFunc(app.createLandedCost))).Methods("POST")
+	r.Handle("/transaction/landedcost/adjust", app.validateToken(http.HandlerFunc(app.adjustLandedCost))).Methods("POST")
+	r.Handle("/transaction/landedcost/type/basis", app.validateToken(http.HandlerFunc(app.setLandedCostAllocationBasis))).Methods("POST")
+	r.Handle("/transaction/landedcost/preview", app.validateToken(http.HandlerFunc(app.previewLandedCost))).Methods("POST")
+
+	r.Handle("/transaction/supplierinvoice/new", app.validateToken(http.HandlerFunc(app.createSupplierInvoice))).Methods("POST")
+	r.Handle("/transaction/supplierinvoice/post", app.validateToken(http.HandlerFunc(app.postSupplierInvoice))).Methods("POST")
+	r.Handle("/transaction/supplierinvoice/reject", app.validateToken(http.HandlerFunc(app.rejectSupplierInvoice))).Methods("POST")
+	r.Handle("/transaction/supplierinvoice/list", app.validateToken(http.HandlerFunc(app.supplierInvoiceList))).Methods("GET")
+	r.Handle("/transaction/supplierinvoice/{siid}", app.validateToken(http.HandlerFunc(app.supplierInvoiceDetails))).Methods("GET")
+	r.Handle("/transaction/supplierreturn/new", app.validateToken(http.HandlerFunc(app.createSupplierReturn))).Methods("POST")
 
 	r.Handle("/transaction/warehousestock/{wid}", app.validateToken(http.HandlerFunc(app.getWarehouseStock))).Methods("GET")
 
@@ -75,10 +121,24 @@ func (app *application) routes() http.Handler {
 	r.Handle("/transaction/inventorytransferitems/{itid}", app.validateToken(http.HandlerFunc(app.inventoryTransferItems))).Methods("GET")
 	r.Handle("/transaction/inventorytransferaction", app.validateToken(http.HandlerFunc(app.inventoryTransferAction))).Methods("POST")
 
+	r.Handle("/transaction/stockadjustment/new", app.validateToken(http.HandlerFunc(app.createStockAdjustment))).Methods("POST")
+
+	r.Handle("/transaction/stocktake/new", app.validateToken(http.HandlerFunc(app.createStockTake))).Methods("POST")
+	r.Handle("/transaction/stocktake/count", app.validateToken(http.HandlerFunc(app.stockTakeCount))).Methods("POST")
+	r.Handle("/transaction/stocktake/approve", app.validateToken(http.HandlerFunc(app.stockTakeApprove))).Methods("POST")
+	r.Handle("/transaction/stocktake/cancel", app.validateToken(http.HandlerFunc(app.stockTakeCancel))).Methods("POST")
+	r.Handle("/transaction/stocktake/list", app.validateToken(http.HandlerFunc(app.stockTakeList))).Methods("GET")
+	r.Handle("/transaction/stocktake/{stid}/variance", app.validateToken(http.HandlerFunc(app.stockTakeVariance))).Methods("GET")
+
 	r.Handle("/transaction/invoice", app.validateToken(http.HandlerFunc(app.createInvoice))).Methods("POST")
 	r.Handle("/transaction/invoice/{iid}", app.validateToken(http.HandlerFunc(app.invoiceDetails))).Methods("GET")
+	r.Handle("/transaction/invoice/{iid}/return", app.validateToken(http.HandlerFunc(app.createInvoiceReturn))).Methods("POST")
+	r.Handle("/transaction/invoice/{iid}/void", app.validateToken(http.HandlerFunc(app.voidInvoice))).Methods("POST")
 
 	r.Handle("/reporting/invoicesearch", app.validateToken(http.HandlerFunc(app.invoiceSearch))).Methods("GET")
+	r.Handle("/reporting/inventoryvaluation", app.validateToken(http.HandlerFunc(app.inventoryValuation))).Methods("GET")
+	r.Handle("/reporting/reorder", app.validateToken(http.HandlerFunc(app.reorderSuggestions))).Methods("GET")
+	r.Handle("/reporting/reorder/purchaseorder", app.validateToken(http.HandlerFunc(app.reorderPurchaseOrder))).Methods("POST")
 
 	r.Handle("/static/", http.StripPrefix("/static", fileServer))
 